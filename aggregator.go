@@ -3,6 +3,7 @@ package aggregator
 import (
 	"context"
 	"errors"
+	"slices"
 	"strings"
 )
 
@@ -16,6 +17,17 @@ const (
 var (
 	ErrNotFoundAggregator = errors.New("not found aggregator")
 	ErrInvalidType        = errors.New("invalid type of aggregator")
+	ErrAggregatorClosed   = errors.New("aggregator is closed")
+	ErrNotSealable        = errors.New("aggregator does not support sealing")
+	ErrCapacityExceeded   = errors.New("aggregator capacity exceeded")
+	ErrNotBounded         = errors.New("aggregator does not support limits")
+	ErrNotGrouped         = errors.New("aggregator does not group items by this key type")
+	ErrNotMergeable       = errors.New("reducer has no merge function")
+	ErrQueueFull          = errors.New("callback queue is full")
+	// ErrCallbackSkipped is returned, wrapping the cause, when the item was
+	// stored but its asynchronous callback will not run. Collecting it again
+	// would store it twice.
+	ErrCallbackSkipped = errors.New("item stored but its callback was skipped")
 )
 
 // FilterFunc is a predicate function that returns true if the item should be included
@@ -24,19 +36,34 @@ type FilterFunc[T any] func(T) bool
 // TransformFunc transforms an item of type T to type R
 type TransformFunc[T any, R any] func(T) R
 
+// ContextAggregator collects items and aggregates them. Aggregate returns a
+// snapshot the caller owns: changing it does not affect the aggregator, and
+// collecting afterwards does not change it.
 type ContextAggregator[T any] interface {
 	Collect(data T)
 	Aggregate() []T
 }
 
+// UnsafeAggregator is implemented by aggregators that can expose their internal
+// storage without copying. The returned slice must not be modified, and it is
+// only safe to read while no other goroutine collects.
+type UnsafeAggregator[T any] interface {
+	ContextAggregator[T]
+	AggregateUnsafe() []T
+}
+
+// Collect collects data into the aggregator of type T. If there is no such
+// aggregator, data is collected into an aggregator whose element type it is
+// assignable to, e.g. a *MyError into an aggregator of error. ErrInvalidType is
+// returned when the aggregator found under keys cannot accept data; CollectAs
+// describes the mismatch with a *TypeMismatchError.
 func Collect[T any](ctx context.Context, data T, keys ...string) error {
 	agg, err := extractAggregator[T](ctx, keys...)
 	if err != nil {
-		return err
+		return collectAssignable(ctx, data, err, keys...)
 	}
 
-	agg.Collect(data)
-	return nil
+	return collectInto(ctx, agg, data)
 }
 
 func Aggregate[T any](ctx context.Context, keys ...string) ([]T, error) {
@@ -48,22 +75,27 @@ func Aggregate[T any](ctx context.Context, keys ...string) ([]T, error) {
 	return agg.Aggregate(), nil
 }
 
-// AggregateWithFilter aggregates only items that match the filter predicate
-func AggregateWithFilter[T any](ctx context.Context, filter FilterFunc[T], keys ...string) ([]T, error) {
+// AggregateUnsafe is Aggregate without the defensive copy, for hot paths that
+// only read the result once collection has finished. The returned slice is the
+// aggregator's internal storage and must not be modified. Aggregators that do
+// not implement UnsafeAggregator return a regular snapshot.
+func AggregateUnsafe[T any](ctx context.Context, keys ...string) ([]T, error) {
 	agg, err := extractAggregator[T](ctx, keys...)
 	if err != nil {
 		return nil, err
 	}
 
-	allItems := agg.Aggregate()
-	filtered := make([]T, 0, len(allItems))
-	for _, item := range allItems {
-		if filter(item) {
-			filtered = append(filtered, item)
-		}
+	return aggregateUnsafe(agg), nil
+}
+
+// AggregateWithFilter aggregates only items that match the filter predicate
+func AggregateWithFilter[T any](ctx context.Context, filter FilterFunc[T], keys ...string) ([]T, error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, err
 	}
 
-	return filtered, nil
+	return filterItems(viewOf(agg), filter), nil
 }
 
 // AggregateWithTransform aggregates and transforms items from type T to type R
@@ -73,13 +105,7 @@ func AggregateWithTransform[T any, R any](ctx context.Context, transform Transfo
 		return nil, err
 	}
 
-	allItems := agg.Aggregate()
-	transformed := make([]R, 0, len(allItems))
-	for _, item := range allItems {
-		transformed = append(transformed, transform(item))
-	}
-
-	return transformed, nil
+	return transformItems(viewOf(agg), transform), nil
 }
 
 // AggregateWithFilterAndTransform filters and transforms items in a single pass
@@ -89,15 +115,42 @@ func AggregateWithFilterAndTransform[T any, R any](ctx context.Context, filter F
 		return nil, err
 	}
 
-	allItems := agg.Aggregate()
-	result := make([]R, 0, len(allItems))
-	for _, item := range allItems {
-		if filter(item) {
-			result = append(result, transform(item))
-		}
+	return filterAndTransformItems(viewOf(agg), filter, transform), nil
+}
+
+func aggregateUnsafe[T any](agg ContextAggregator[T]) []T {
+	if unsafeAgg, ok := agg.(UnsafeAggregator[T]); ok {
+		return unsafeAgg.AggregateUnsafe()
 	}
 
-	return result, nil
+	return agg.Aggregate()
+}
+
+func filterItems[T any](items []T, filter FilterFunc[T]) []T {
+	return slices.AppendSeq(make([]T, 0, len(items)), Filter(slices.Values(items), filter))
+}
+
+func transformItems[T any, R any](items []T, transform TransformFunc[T, R]) []R {
+	return slices.AppendSeq(make([]R, 0, len(items)), Map(slices.Values(items), transform))
+}
+
+func filterAndTransformItems[T any, R any](items []T, filter FilterFunc[T], transform TransformFunc[T, R]) []R {
+	return slices.AppendSeq(make([]R, 0, len(items)), Map(Filter(slices.Values(items), filter), transform))
+}
+
+// defaultContextKey is the context key of an aggregator registered without keys.
+// It is a distinct type for every T, so keyless aggregators of different types
+// can be registered in the same context without shadowing each other.
+type defaultContextKey[T any] struct{}
+
+// typedContextKey builds the context key of an aggregator of type T: the per-type
+// default key when no keys are given, otherwise the key built by buildContextKey
+func typedContextKey[T any](keys ...string) any {
+	if len(keys) == 0 {
+		return defaultContextKey[T]{}
+	}
+
+	return buildContextKey(keys...)
 }
 
 // buildContextKey builds context key from default context key and input keys
@@ -106,13 +159,21 @@ func buildContextKey(keys ...string) contextKey {
 		return contextAggregatorContextKey
 	}
 
-	keys = append([]string{string(contextAggregatorContextKey)}, keys...)
-	return contextKey(strings.Join(keys, "_"))
+	if len(keys) == 1 {
+		return contextAggregatorContextKey + "_" + contextKey(keys[0])
+	}
+
+	return contextAggregatorContextKey + "_" + contextKey(strings.Join(keys, "_"))
 }
 
 func extractAggregator[T any](ctx context.Context, keys ...string) (ContextAggregator[T], error) {
-	ctxKey := buildContextKey(keys...)
-	aggVal := ctx.Value(ctxKey)
+	return lookupAggregator[T](ctx, typedContextKey[T](keys...))
+}
+
+// lookupAggregator finds the aggregator stored under ctxKey, which is either a
+// key built by typedContextKey or a *Key[T]
+func lookupAggregator[T any](ctx context.Context, ctxKey any) (ContextAggregator[T], error) {
+	aggVal := lookupValue(ctx, ctxKey)
 	if aggVal == nil {
 		return nil, ErrNotFoundAggregator
 	}
@@ -0,0 +1,98 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// TypeMismatchError reports that a value cannot be collected because its type
+// is not assignable to the element type of the aggregator found in the context.
+// It wraps ErrInvalidType.
+type TypeMismatchError struct {
+	Value reflect.Type
+	Elem  reflect.Type
+}
+
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("%s: %v is not assignable to %v", ErrInvalidType, e.Value, e.Elem)
+}
+
+func (e *TypeMismatchError) Unwrap() error {
+	return ErrInvalidType
+}
+
+// CollectAs collects data into the aggregator of type I, which is usually an
+// interface type such as error. It returns a *TypeMismatchError if data does not
+// implement I.
+func CollectAs[I any](ctx context.Context, data any, keys ...string) error {
+	value, ok := data.(I)
+	if !ok {
+		return &TypeMismatchError{Value: reflect.TypeOf(data), Elem: reflect.TypeFor[I]()}
+	}
+
+	return Collect(ctx, value, keys...)
+}
+
+// collectAssignable is the fallback of Collect when no aggregator of exactly the
+// type of data is found. Without keys it looks for the most recently registered
+// keyless aggregator of an interface type data implements; with keys it accepts
+// an aggregator whose element type data is assignable to.
+func collectAssignable(ctx context.Context, data any, cause error, keys ...string) error {
+	valueType := reflect.TypeOf(data)
+	if valueType == nil {
+		return cause
+	}
+
+	switch {
+	case errors.Is(cause, ErrNotFoundAggregator) && len(keys) == 0:
+		if reg := registryFrom(ctx); reg != nil {
+			for _, entry := range reg.interfaces {
+				if valueType.AssignableTo(entry.elem) {
+					return collectReflect(ctx, entry.agg, data)
+				}
+			}
+		}
+	case errors.Is(cause, ErrInvalidType):
+		return collectReflect(ctx, lookupValue(ctx, buildContextKey(keys...)), data)
+	}
+
+	return cause
+}
+
+// collectReflect calls the Collect method of agg with data if data is assignable
+// to its parameter. Aggregators whose collection can block collect with ctx, so
+// it can be cancelled, and TryCollect is preferred to Collect to report errors of
+// fallible aggregators. It returns ErrInvalidType otherwise, as Collect always did.
+func collectReflect(ctx context.Context, agg any, data any) error {
+	aggValue := reflect.ValueOf(agg)
+	collect := aggValue.MethodByName("Collect")
+	if !collect.IsValid() || collect.Type().NumIn() != 1 {
+		return ErrInvalidType
+	}
+
+	elem := collect.Type().In(0)
+	valueType := reflect.TypeOf(data)
+	if !valueType.AssignableTo(elem) {
+		return ErrInvalidType
+	}
+
+	if blocking, ok := agg.(anyContextCollector); ok {
+		value := reflect.New(elem).Elem()
+		value.Set(reflect.ValueOf(data))
+		return blocking.collectContextAny(ctx, value.Interface())
+	}
+
+	args := []reflect.Value{reflect.ValueOf(data)}
+	tryCollect := aggValue.MethodByName("TryCollect")
+	if tryCollect.IsValid() && tryCollect.Type() == reflect.FuncOf([]reflect.Type{elem}, []reflect.Type{errorType}, false) {
+		err, _ := tryCollect.Call(args)[0].Interface().(error)
+		return err
+	}
+
+	collect.Call(args)
+	return nil
+}
+
+var errorType = reflect.TypeFor[error]()
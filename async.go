@@ -0,0 +1,517 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"slices"
+	"sync"
+)
+
+// defaultQueueSize is the number of queued callbacks when WithAsyncCallbacks
+// is given no queue size
+const defaultQueueSize = 1024
+
+// WithAsyncCallbacks makes a streaming aggregator run its callbacks on workers
+// goroutines fed through a queue of queueSize items, instead of inside Collect
+// under the aggregator lock, so one slow callback no longer holds back every
+// collector. workers of zero or less uses a single goroutine, which runs the
+// callbacks in collect order; queueSize of zero or less uses 1024. It implies
+// WithConcurrency.
+//
+// When the queue is full, policy applies to the callback: OverflowBlock waits
+// for room, without holding the aggregator lock, until the collect context is
+// done, OverflowDropNewest skips the callback of the new item,
+// OverflowDropOldest skips the oldest queued one, which is a whole batch for
+// CollectMany, and OverflowError skips it. Items are stored whatever happens to
+// their callback: a collector giving up on its callback gets ErrCallbackSkipped
+// wrapping ErrQueueFull or the context error, and must not collect the item
+// again. Inspect reports skipped callbacks.
+//
+// The workers run the queued callbacks and stop once the aggregator is sealed,
+// or stop right away once the context given to Register is done. Aggregators
+// created with New, or registered into a context that is never done, must be
+// sealed or closed with Close so the workers do not outlive them. Flush waits
+// for the queued callbacks.
+func WithAsyncCallbacks(workers, queueSize int, policy OverflowPolicy) Option {
+	return func(c *config) {
+		c.concurrent = true
+		c.async = true
+		c.workers = workers
+		c.queueSize = queueSize
+		c.queuePolicy = policy
+	}
+}
+
+// WithOrderKey makes the asynchronous callbacks of items with the same key run
+// one at a time in collect order, by handing them all to the same worker. Items
+// with different keys still run in parallel. It only applies with
+// WithAsyncCallbacks, but the item type must match the type parameter given to
+// Register in any case.
+func WithOrderKey[T any, K comparable](keyFn func(T) K) Option {
+	seed := maphash.MakeSeed()
+	return func(c *config) {
+		c.orderKey = func(item T) uint64 {
+			return maphash.Comparable(seed, keyFn(item))
+		}
+	}
+}
+
+// FlushableAggregator is implemented by aggregators running callbacks
+// asynchronously
+type FlushableAggregator[T any] interface {
+	ContextAggregator[T]
+	// Flush waits until every queued callback has run, including the ones
+	// queued while waiting, or until ctx is done
+	Flush(ctx context.Context) error
+	// Close seals the aggregator and waits until the workers ran the queued
+	// callbacks and exited, or until ctx is done
+	Close(ctx context.Context) error
+}
+
+// Flush waits until the queued callbacks of the aggregator of type T have run.
+// It returns right away for aggregators running their callbacks synchronously,
+// and with the context error if the workers were stopped by the context given
+// to Register.
+func Flush[T any](ctx context.Context, keys ...string) error {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return err
+	}
+
+	if flushable, ok := agg.(FlushableAggregator[T]); ok {
+		return flushable.Flush(ctx)
+	}
+
+	return nil
+}
+
+// Close seals the aggregator of type T and waits until its asynchronous
+// callbacks have run and its workers exited, or until ctx is done. Aggregators
+// running their callbacks synchronously are only sealed.
+func Close[T any](ctx context.Context, keys ...string) error {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return err
+	}
+
+	if flushable, ok := agg.(FlushableAggregator[T]); ok {
+		return flushable.Close(ctx)
+	}
+
+	sealable, ok := agg.(SealableAggregator[T])
+	if !ok {
+		return ErrNotSealable
+	}
+
+	sealable.Seal()
+	return nil
+}
+
+// task is a callback waiting in a queue, for a single item or a batch
+type task[T any] struct {
+	item  T
+	items []T
+	queue *queue[T]
+	// seq is the position of the task in queue
+	seq uint64
+	// skipped is set when its collector gave up waiting for room
+	skipped bool
+}
+
+// len returns the number of items whose callbacks t runs
+func (t *task[T]) len() int {
+	if t.items == nil {
+		return 1
+	}
+
+	return len(t.items)
+}
+
+// queue is a FIFO of tasks taken by the workers. It grows past the queue size
+// only by the tasks of collectors blocked by OverflowBlock, one each, so they
+// keep their place in collect order without holding the aggregator lock.
+type queue[T any] struct {
+	tasks []*task[T]
+	// pushed and taken count the tasks pushed to and taken from the queue
+	pushed uint64
+	taken  uint64
+	// wake is signalled when tasks are pushed
+	wake chan struct{}
+}
+
+// dispatcher runs callbacks on worker goroutines. Tasks are pushed under the
+// lock of the aggregator, so queues keep collect order; with an order key, all
+// the items of a key go through the queue of a single worker. The queues are
+// guarded by m, never held while a callback runs or a collector waits.
+type dispatcher[T any] struct {
+	callbacks[T]
+	queues   []*queue[T]
+	size     int
+	policy   OverflowPolicy
+	orderKey func(T) uint64
+
+	// stop is closed once ctx, the context given to Register, is done
+	ctx       context.Context
+	stop      chan struct{}
+	stopAfter func() bool
+	// closing is closed on Seal, exited once every worker returned
+	closing chan struct{}
+	exited  chan struct{}
+
+	m       sync.Mutex
+	closed  bool
+	running int
+	pending int
+	skipped int
+	// idle is closed when no callback is pending, waking Flush, and room when
+	// tasks are taken, waking blocked collectors. They are created by the first
+	// of them.
+	idle chan struct{}
+	room chan struct{}
+}
+
+func newDispatcher[T any](ctx context.Context, cfg *config, cb callbacks[T], orderKey func(T) uint64) *dispatcher[T] {
+	workers := max(cfg.workers, 1)
+	size := cfg.queueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	d := &dispatcher[T]{
+		callbacks: cb,
+		policy:    cfg.queuePolicy,
+		orderKey:  orderKey,
+		ctx:       ctx,
+		stop:      make(chan struct{}),
+		closing:   make(chan struct{}),
+		exited:    make(chan struct{}),
+		running:   workers,
+	}
+
+	// Without an order key the workers share a queue, so an idle worker takes
+	// the next callback; with one every worker has its share of the queue
+	queues := 1
+	if d.orderKey != nil {
+		queues = workers
+		size = max(size/workers, 1)
+	}
+	d.size = size
+	d.queues = make([]*queue[T], queues)
+	for i := range d.queues {
+		d.queues[i] = &queue[T]{wake: make(chan struct{}, 1)}
+	}
+
+	for i := range workers {
+		go d.work(d.queues[i%queues])
+	}
+	d.stopAfter = context.AfterFunc(ctx, func() {
+		close(d.stop)
+	})
+
+	return d
+}
+
+func (d *dispatcher[T]) work(q *queue[T]) {
+	defer d.exit()
+
+	var buf [1]T
+	for {
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+
+		t, ok := d.take(q)
+		if !ok {
+			return
+		}
+		if t == nil {
+			select {
+			case <-q.wake:
+			case <-d.closing:
+			case <-d.stop:
+				return
+			}
+			continue
+		}
+
+		if t.items != nil {
+			d.deliverMany(t.items)
+		} else {
+			d.deliver(t.item, &buf)
+		}
+		d.finish()
+	}
+}
+
+// take returns the next task of q that was not skipped, nil if there is none
+// yet, or false once the queues are closed and q is empty
+func (d *dispatcher[T]) take(q *queue[T]) (*task[T], bool) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	for len(q.tasks) > 0 {
+		t := q.tasks[0]
+		q.tasks[0] = nil
+		q.tasks = q.tasks[1:]
+		q.taken++
+		d.notify(&d.room)
+
+		if len(q.tasks) > 0 {
+			// Let another worker of a shared queue take the next one
+			signal(q.wake)
+		}
+		if !t.skipped {
+			return t, true
+		}
+		d.done()
+	}
+
+	return nil, !d.closed
+}
+
+// dispatch queues the callback of a stored item. The caller must hold the
+// aggregator mutex, and then call await with the returned task, if any, once
+// it released the mutex.
+func (d *dispatcher[T]) dispatch(data T) (*task[T], error) {
+	return d.push(&task[T]{item: data, queue: d.queueOf(data)})
+}
+
+// dispatchMany is dispatch for a stored batch, split by worker when there is
+// an order key. The batch is copied since the caller owns it.
+func (d *dispatcher[T]) dispatchMany(items []T) ([]*task[T], error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	tasks := []*task[T]{{items: slices.Clone(items), queue: d.queues[0]}}
+	if d.orderKey != nil {
+		batches := make(map[*queue[T]]*task[T], len(d.queues))
+		tasks = tasks[:0]
+		for _, item := range items {
+			q := d.queueOf(item)
+			t, ok := batches[q]
+			if !ok {
+				t = &task[T]{queue: q}
+				batches[q] = t
+				tasks = append(tasks, t)
+			}
+			t.items = append(t.items, item)
+		}
+	}
+
+	var waiting []*task[T]
+	var err error
+	for _, t := range tasks {
+		t, pushErr := d.push(t)
+		if t != nil {
+			waiting = append(waiting, t)
+		}
+		if err == nil {
+			err = pushErr
+		}
+	}
+
+	return waiting, err
+}
+
+func (d *dispatcher[T]) queueOf(data T) *queue[T] {
+	if d.orderKey == nil {
+		return d.queues[0]
+	}
+
+	return d.queues[d.orderKey(data)%uint64(len(d.queues))]
+}
+
+// push appends t to its queue, applying the policy when the queue is full. It
+// returns t if its collector must wait for room with await. Once the workers
+// are stopped callbacks are skipped.
+func (d *dispatcher[T]) push(t *task[T]) (*task[T], error) {
+	q := t.queue
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	select {
+	case <-d.stop:
+		d.skipped += t.len()
+		return nil, nil
+	default:
+	}
+
+	wait := false
+	if len(q.tasks) >= d.size {
+		switch d.policy {
+		case OverflowBlock:
+			wait = true
+		case OverflowDropOldest:
+			for len(q.tasks) >= d.size {
+				d.skipped += q.tasks[0].len()
+				q.tasks[0] = nil
+				q.tasks = q.tasks[1:]
+				q.taken++
+				d.done()
+			}
+		case OverflowError:
+			d.skipped += t.len()
+			return nil, fmt.Errorf("%w: %w", ErrCallbackSkipped, ErrQueueFull)
+		default:
+			d.skipped += t.len()
+			return nil, nil
+		}
+	}
+
+	t.seq = q.pushed
+	q.pushed++
+	q.tasks = append(q.tasks, t)
+	d.pending++
+	signal(q.wake)
+
+	if !wait {
+		return nil, nil
+	}
+	return t, nil
+}
+
+// await waits until t, pushed by OverflowBlock, fits in the queue size. The
+// caller must not hold the aggregator mutex.
+func (d *dispatcher[T]) await(ctx context.Context, t *task[T]) error {
+	q := t.queue
+	for {
+		d.m.Lock()
+		if t.seq < q.taken+uint64(d.size) {
+			d.m.Unlock()
+			return nil
+		}
+		if d.room == nil {
+			d.room = make(chan struct{})
+		}
+		room := d.room
+		d.m.Unlock()
+
+		select {
+		case <-room:
+		case <-d.stop:
+			return d.skip(q, t, nil)
+		case <-ctx.Done():
+			return d.skip(q, t, ctx.Err())
+		}
+	}
+}
+
+// skip gives up on t unless it fits in the queue by now, returning cause
+// wrapped in ErrCallbackSkipped, or nil without a cause
+func (d *dispatcher[T]) skip(q *queue[T], t *task[T], cause error) error {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if t.seq < q.taken+uint64(d.size) {
+		return nil
+	}
+
+	t.skipped = true
+	d.skipped += t.len()
+	if cause == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrCallbackSkipped, cause)
+}
+
+// finish marks a callback as run
+func (d *dispatcher[T]) finish() {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	d.done()
+}
+
+// done marks a callback as no longer pending. The caller must hold m.
+func (d *dispatcher[T]) done() {
+	d.pending--
+	if d.pending == 0 {
+		d.notify(&d.idle)
+	}
+}
+
+// notify closes *ch, if created, waking its waiters. The caller must hold m.
+func (d *dispatcher[T]) notify(ch *chan struct{}) {
+	if *ch != nil {
+		close(*ch)
+		*ch = nil
+	}
+}
+
+func (d *dispatcher[T]) exit() {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	d.running--
+	if d.running == 0 {
+		close(d.exited)
+	}
+}
+
+func (d *dispatcher[T]) flush(ctx context.Context) error {
+	d.m.Lock()
+	if d.pending == 0 {
+		d.m.Unlock()
+		return nil
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.m.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-d.stop:
+		return context.Cause(d.ctx)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close lets the workers run the queued callbacks and exit. The caller must
+// hold the aggregator mutex, so nothing is pushed afterwards.
+func (d *dispatcher[T]) close() {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	d.closed = true
+	d.stopAfter()
+	close(d.closing)
+}
+
+// wait waits until the workers exited
+func (d *dispatcher[T]) wait(ctx context.Context) error {
+	select {
+	case <-d.exited:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// skippedCallbacks returns the number of items whose callbacks were skipped so
+// far
+func (d *dispatcher[T]) skippedCallbacks() int {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	return d.skipped
+}
+
+// signal wakes a goroutine waiting on ch without blocking
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
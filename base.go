@@ -5,37 +5,72 @@ import (
 )
 
 var _ ContextAggregator[any] = new(baseAggregator[any])
+var _ UnsafeAggregator[any] = new(baseAggregator[any])
+var _ SealableAggregator[any] = new(baseAggregator[any])
+var _ BoundedAggregator[any] = new(baseAggregator[any])
+var _ inspector = new(baseAggregator[any])
+var _ viewer[any] = new(baseAggregator[any])
 
 // RegisterBaseContextAggregator register a baseAggregator pointer into context
 // for collecting and aggregating data sequentially without any asynchronous
 // lock. In order to use many aggregators in a project, please use different keys.
 func RegisterBaseContextAggregator[T any](ctx context.Context, keys ...string) context.Context {
-	agg := &baseAggregator[T]{
-		datas: make([]T, 0),
-	}
-	ctxKey := buildContextKey(keys...)
-	return context.WithValue(ctx, ctxKey, agg)
+	return Register[T](ctx, WithKey(keys...))
 }
 
 // RegisterBaseContextAggregatorWithCapacity register a baseAggregator pointer into context
 // with a capacity hint for pre-allocation. This can improve performance when the expected
 // number of items is known in advance, reducing memory allocations.
 func RegisterBaseContextAggregatorWithCapacity[T any](ctx context.Context, capacity int, keys ...string) context.Context {
-	agg := &baseAggregator[T]{
-		datas: make([]T, 0, capacity),
-	}
-	ctxKey := buildContextKey(keys...)
-	return context.WithValue(ctx, ctxKey, agg)
+	return Register[T](ctx, WithCapacity(capacity), WithKey(keys...))
 }
 
 type baseAggregator[T any] struct {
-	datas []T
+	store[T]
 }
 
 func (a *baseAggregator[T]) Collect(data T) {
-	a.datas = append(a.datas, data)
+	_ = a.TryCollect(data)
+}
+
+func (a *baseAggregator[T]) TryCollect(data T) error {
+	return a.settle(data, a.addSequential(data))
+}
+
+func (a *baseAggregator[T]) collectMany(_ context.Context, items []T) error {
+	n, err := a.addManySequential(items)
+	return a.settleMany(items, n, err)
 }
 
 func (a *baseAggregator[T]) Aggregate() []T {
+	return a.snapshot()
+}
+
+func (a *baseAggregator[T]) AggregateUnsafe() []T {
 	return a.datas
 }
+
+func (a *baseAggregator[T]) AggregateWithDropped() ([]T, int) {
+	return a.snapshot(), a.dropped
+}
+
+func (a *baseAggregator[T]) Drain() []T {
+	return a.drain()
+}
+
+func (a *baseAggregator[T]) Seal() {
+	a.seal()
+}
+
+func (a *baseAggregator[T]) AggregateAndSeal() []T {
+	a.seal()
+	return a.snapshot()
+}
+
+func (a *baseAggregator[T]) LateCollects() int {
+	return a.late
+}
+
+func (a *baseAggregator[T]) inspect() AggregatorInfo {
+	return a.info(KindBase)
+}
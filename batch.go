@@ -0,0 +1,108 @@
+package aggregator
+
+import (
+	"context"
+)
+
+// BatchCallback is a function that is called with every batch of collected items
+type BatchCallback[T any] func([]T)
+
+// batchCollector is implemented by aggregators that store a batch at once
+type batchCollector[T any] interface {
+	collectMany(ctx context.Context, items []T) error
+}
+
+// WithBatchCallback sets a callback invoked synchronously with the stored items
+// of every CollectMany call, and with a single item for every Collect. It can be
+// combined with WithCallback, which still sees items one by one. The callback
+// element type must match the type parameter given to Register.
+//
+// The slice is only valid during the call: it is reused for the next item or
+// owned by the collector, so the callback must copy the items it keeps.
+func WithBatchCallback[T any](callback BatchCallback[T]) Option {
+	return func(c *config) {
+		c.batch = callback
+	}
+}
+
+// CollectMany collects items into the aggregator of type T with a single lookup
+// and, for the built-in aggregators, a single lock acquisition; sharded and
+// concurrent reservoir aggregators put the whole batch into one shard. Limits apply as
+// if items were collected one by one: with OverflowError the items that fit are
+// stored and ErrCapacityExceeded is returned for the rest.
+//
+// Unlike Collect, CollectMany does not fall back to an aggregator of another
+// type. Aggregators collecting one item at a time stop at the first error.
+func CollectMany[T any](ctx context.Context, items []T, keys ...string) error {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return err
+	}
+
+	return collectManyInto(ctx, agg, items)
+}
+
+func collectManyInto[T any](ctx context.Context, agg ContextAggregator[T], items []T) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	if batch, ok := agg.(batchCollector[T]); ok {
+		return batch.collectMany(ctx, items)
+	}
+
+	for _, item := range items {
+		if err := collectInto(ctx, agg, item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// callbacks holds the callbacks of streaming aggregators
+type callbacks[T any] struct {
+	callback CollectCallback[T]
+	batch    BatchCallback[T]
+}
+
+func newCallbacks[T any](cfg *config) callbacks[T] {
+	return callbacks[T]{
+		callback: typedOption[CollectCallback[T]](cfg.callback, "callback"),
+		batch:    typedOption[BatchCallback[T]](cfg.batch, "batch callback"),
+	}
+}
+
+func (c callbacks[T]) any() bool {
+	return c.callback != nil || c.batch != nil
+}
+
+// deliver hands a stored item to the callbacks. The batch callback gets it in
+// buf, so that collecting does not allocate; buf is cleared afterwards so the
+// item is not kept alive.
+func (c callbacks[T]) deliver(data T, buf *[1]T) {
+	if c.callback != nil {
+		invokeCallback(c.callback, data)
+	}
+	if c.batch != nil {
+		buf[0] = data
+		invokeCallback(CollectCallback[[]T](c.batch), buf[:])
+		clear(buf[:])
+	}
+}
+
+// deliverMany hands a batch of stored items to the callbacks
+func (c callbacks[T]) deliverMany(items []T) {
+	if len(items) == 0 {
+		return
+	}
+
+	if c.callback != nil {
+		for _, item := range items {
+			invokeCallback(c.callback, item)
+		}
+	}
+	if c.batch != nil {
+		invokeCallback(CollectCallback[[]T](c.batch), items)
+	}
+}
@@ -0,0 +1,93 @@
+package aggregator
+
+import (
+	"context"
+)
+
+// OverflowPolicy decides what happens when an item is collected into an
+// aggregator that already holds its maximum number of items
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest silently discards the item being collected
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest stored item to make room
+	OverflowDropOldest
+	// OverflowError rejects the item with ErrCapacityExceeded
+	OverflowError
+	// OverflowBlock makes Collect wait until Drain frees space, the aggregator
+	// is sealed or the collecting context is done. Sequential aggregators have
+	// nobody to free space while their only collector waits, so they reject the
+	// item with ErrCapacityExceeded instead. While Aggregate waits for the
+	// waiters registered with WaitFunc, blocked collectors fail with
+	// ErrCapacityExceeded too: one of them may hold a waiter, which would keep
+	// Aggregate from ever returning.
+	OverflowBlock
+)
+
+// BoundedAggregator is implemented by aggregators that can be limited with
+// WithMaxItems. All the built-in aggregators are bounded aggregators.
+type BoundedAggregator[T any] interface {
+	ContextAggregator[T]
+	// AggregateWithDropped returns a snapshot along with the number of items
+	// discarded so far because of the limit
+	AggregateWithDropped() ([]T, int)
+	// Drain returns every stored item and empties the aggregator, freeing space
+	// for collectors blocked by OverflowBlock
+	Drain() []T
+}
+
+// contextCollector is implemented by aggregators whose collection can block,
+// so it can be cancelled through the collecting context
+type contextCollector[T any] interface {
+	collectContext(ctx context.Context, data T) error
+}
+
+// anyContextCollector is contextCollector for callers that only know the
+// element type at run time, such as the assignable fallback of Collect. data
+// must be of the element type.
+type anyContextCollector interface {
+	collectContextAny(ctx context.Context, data any) error
+}
+
+// WithMaxItems limits the number of items the aggregator keeps. Once the limit
+// is reached, policy decides what happens to new items. A limit of zero or less
+// means unbounded.
+func WithMaxItems(maxItems int, policy OverflowPolicy) Option {
+	return func(c *config) {
+		c.maxItems = maxItems
+		c.policy = policy
+	}
+}
+
+// AggregateWithDropped aggregates data along with the number of items the
+// aggregator discarded because of its limit. Aggregators that do not implement
+// BoundedAggregator never drop items.
+func AggregateWithDropped[T any](ctx context.Context, keys ...string) ([]T, int, error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if bounded, ok := agg.(BoundedAggregator[T]); ok {
+		items, dropped := bounded.AggregateWithDropped()
+		return items, dropped, nil
+	}
+
+	return agg.Aggregate(), 0, nil
+}
+
+// Drain returns every item collected so far and empties the aggregator
+func Drain[T any](ctx context.Context, keys ...string) ([]T, error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	bounded, ok := agg.(BoundedAggregator[T])
+	if !ok {
+		return nil, ErrNotBounded
+	}
+
+	return bounded.Drain(), nil
+}
@@ -0,0 +1,250 @@
+package aggregator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+var _ batchCollector[any] = new(sequential[any, *ring[any]])
+var _ batchCollector[any] = new(synchronized[any, *ring[any]])
+
+// collection is the storage of a special aggregator, such as a ring buffer or
+// a heap. It only decides how items are kept; sequential and synchronized add
+// the lifecycle, locking and waiters every aggregator shares. It is not
+// synchronized.
+type collection[T any] interface {
+	add(data T)
+	// snapshot returns a copy of the kept items
+	snapshot() []T
+	// info describes the kept items: Len, Cap, MaxItems and Dropped
+	info() AggregatorInfo
+}
+
+// boundedCollection is a collection discarding items, which can be drained
+type boundedCollection[T any] interface {
+	collection[T]
+	// drain returns the kept items and empties the collection
+	drain() []T
+	// dropped returns the number of discarded items
+	dropped() int
+}
+
+// sequential is a special aggregator without any lock
+type sequential[T any, C collection[T]] struct {
+	lifecycle[T]
+	items C
+	kind  Kind
+}
+
+func (a *sequential[T, C]) init(items C, kind Kind, cfg *config) {
+	a.items = items
+	a.kind = kind
+	a.lifecycle = newLifecycle[T](cfg)
+}
+
+func (a *sequential[T, C]) Collect(data T) {
+	_ = a.TryCollect(data)
+}
+
+func (a *sequential[T, C]) TryCollect(data T) error {
+	err := a.reject(1)
+	if err == nil {
+		a.items.add(data)
+	}
+
+	return a.settle(data, err)
+}
+
+func (a *sequential[T, C]) collectMany(_ context.Context, items []T) error {
+	err := a.reject(len(items))
+	if err == nil {
+		for _, item := range items {
+			a.items.add(item)
+		}
+	}
+
+	return a.settleMany(items, err)
+}
+
+func (a *sequential[T, C]) Aggregate() []T {
+	return a.items.snapshot()
+}
+
+func (a *sequential[T, C]) Seal() {
+	a.sealed = true
+}
+
+func (a *sequential[T, C]) AggregateAndSeal() []T {
+	a.sealed = true
+	return a.items.snapshot()
+}
+
+func (a *sequential[T, C]) LateCollects() int {
+	return a.late
+}
+
+func (a *sequential[T, C]) inspect() AggregatorInfo {
+	return describe(a.items, a.kind, &a.lifecycle)
+}
+
+// sequentialBounded is a sequential aggregator over a bounded collection
+type sequentialBounded[T any, C boundedCollection[T]] struct {
+	sequential[T, C]
+}
+
+func (a *sequentialBounded[T, C]) AggregateWithDropped() ([]T, int) {
+	return a.items.snapshot(), a.items.dropped()
+}
+
+func (a *sequentialBounded[T, C]) Drain() []T {
+	return a.items.drain()
+}
+
+// waitGroup tracks the AddWait calls not yet matched by Done, which concurrent
+// aggregators wait for before reading
+type waitGroup struct {
+	wg      sync.WaitGroup
+	waiters atomic.Int64
+}
+
+func (w *waitGroup) AddWait() {
+	w.waiters.Add(1)
+	w.wg.Add(1)
+}
+
+func (w *waitGroup) Done() {
+	w.waiters.Add(-1)
+	w.wg.Done()
+}
+
+// synchronized is a special aggregator guarding its collection with a mutex
+type synchronized[T any, C collection[T]] struct {
+	m sync.Mutex
+	waitGroup
+	lifecycle[T]
+	items C
+	kind  Kind
+}
+
+func (a *synchronized[T, C]) init(items C, kind Kind, cfg *config) {
+	a.items = items
+	a.kind = kind
+	a.lifecycle = newLifecycle[T](cfg)
+}
+
+func (a *synchronized[T, C]) Collect(data T) {
+	_ = a.TryCollect(data)
+}
+
+func (a *synchronized[T, C]) TryCollect(data T) error {
+	return a.settle(data, a.add(data))
+}
+
+// add stores data under the lock. The collection may call user functions, such
+// as a key function, so the lock is released even if they panic.
+func (a *synchronized[T, C]) add(data T) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if err := a.reject(1); err != nil {
+		return err
+	}
+
+	a.items.add(data)
+	return nil
+}
+
+func (a *synchronized[T, C]) collectMany(_ context.Context, items []T) error {
+	return a.settleMany(items, a.addMany(items))
+}
+
+// addMany stores a batch under a single lock acquisition
+func (a *synchronized[T, C]) addMany(items []T) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if err := a.reject(len(items)); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		a.items.add(item)
+	}
+	return nil
+}
+
+// lockAfterWait waits for the waiters, then locks the mutex
+func (a *synchronized[T, C]) lockAfterWait() {
+	// Always call Wait before lock mutex for not cause deadlock
+	// between syncgroup and mutex
+	a.wg.Wait()
+	a.m.Lock()
+}
+
+func (a *synchronized[T, C]) Aggregate() []T {
+	a.lockAfterWait()
+	defer a.m.Unlock()
+
+	return a.items.snapshot()
+}
+
+func (a *synchronized[T, C]) Seal() {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	a.sealed = true
+}
+
+func (a *synchronized[T, C]) AggregateAndSeal() []T {
+	a.lockAfterWait()
+	defer a.m.Unlock()
+
+	a.sealed = true
+	return a.items.snapshot()
+}
+
+func (a *synchronized[T, C]) LateCollects() int {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	return a.late
+}
+
+func (a *synchronized[T, C]) inspect() AggregatorInfo {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	info := describe(a.items, a.kind, &a.lifecycle)
+	info.Waiters = int(a.waiters.Load())
+	return info
+}
+
+// synchronizedBounded is a synchronized aggregator over a bounded collection
+type synchronizedBounded[T any, C boundedCollection[T]] struct {
+	synchronized[T, C]
+}
+
+func (a *synchronizedBounded[T, C]) AggregateWithDropped() ([]T, int) {
+	a.lockAfterWait()
+	defer a.m.Unlock()
+
+	return a.items.snapshot(), a.items.dropped()
+}
+
+func (a *synchronizedBounded[T, C]) Drain() []T {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	return a.items.drain()
+}
+
+// describe completes the info of a collection with its lifecycle
+func describe[T any](items collection[T], kind Kind, l *lifecycle[T]) AggregatorInfo {
+	info := items.info()
+	info.ElemType = elemTypeName[T]()
+	info.Kind = kind
+	info.Sealed = l.sealed
+	info.LateCollects = l.late
+	return info
+}
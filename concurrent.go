@@ -4,43 +4,37 @@ import (
 	"context"
 	"runtime"
 	"sync"
+	"sync/atomic"
 )
 
 var _ ContextAggregator[any] = new(concurrentAggregator[any])
-var _ IConcurrentAggregator = new(concurrentAggregator[any])
+var _ ConcurrentContextAggregator[any] = new(concurrentAggregator[any])
+var _ UnsafeAggregator[any] = new(concurrentAggregator[any])
+var _ SealableAggregator[any] = new(concurrentAggregator[any])
+var _ BoundedAggregator[any] = new(concurrentAggregator[any])
+var _ inspector = new(concurrentAggregator[any])
+var _ viewer[any] = new(concurrentAggregator[any])
+var _ anyContextCollector = new(concurrentAggregator[any])
 
 // RegisterConcurrentContextAggregator register a concurrentAggregator pointer into context
 // for collecting and aggregating data asynchronously from multiple goroutines.
 // In order to use many aggregators in a project, please use different keys.
 func RegisterConcurrentContextAggregator[T any](ctx context.Context, keys ...string) context.Context {
-	agg := &concurrentAggregator[T]{
-		m:     &sync.Mutex{},
-		wg:    &sync.WaitGroup{},
-		datas: make([]T, 0),
-	}
-
-	ctxKey := buildContextKey(keys...)
-	return context.WithValue(ctx, ctxKey, agg)
+	return Register[T](ctx, WithConcurrency(), WithKey(keys...))
 }
 
 // RegisterConcurrentContextAggregatorWithCapacity register a concurrentAggregator pointer into context
 // with a capacity hint for pre-allocation. This can improve performance when the expected
 // number of items is known in advance, reducing memory allocations.
 func RegisterConcurrentContextAggregatorWithCapacity[T any](ctx context.Context, capacity int, keys ...string) context.Context {
-	agg := &concurrentAggregator[T]{
-		m:     &sync.Mutex{},
-		wg:    &sync.WaitGroup{},
-		datas: make([]T, 0, capacity),
-	}
-
-	ctxKey := buildContextKey(keys...)
-	return context.WithValue(ctx, ctxKey, agg)
+	return Register[T](ctx, WithConcurrency(), WithCapacity(capacity), WithKey(keys...))
 }
 
 type concurrentAggregator[T any] struct {
-	m     *sync.Mutex
-	wg    *sync.WaitGroup
-	datas []T
+	m       *sync.Mutex
+	wg      *sync.WaitGroup
+	waiters atomic.Int64
+	store[T]
 }
 
 type IConcurrentAggregator interface {
@@ -48,13 +42,20 @@ type IConcurrentAggregator interface {
 	Done()
 }
 
+// ConcurrentContextAggregator is a ContextAggregator safe for concurrent use whose
+// Aggregate waits for every AddWait to be matched by a Done
+type ConcurrentContextAggregator[T any] interface {
+	ContextAggregator[T]
+	IConcurrentAggregator
+}
+
 // WaitContextFinalizer current cannot used due to
 // context cannot passed into runtime.SetFinalizer
 // fatal error: cannot pass *context.valueCtx to finalizer func()
 
 func WaitContextFinalizer(ctx context.Context, keys ...string) context.Context {
 	ctxKey := buildContextKey(keys...)
-	aggVal := ctx.Value(ctxKey)
+	aggVal := lookupValue(ctx, ctxKey)
 	if aggVal == nil {
 		return ctx
 	}
@@ -71,12 +72,35 @@ func WaitContextFinalizer(ctx context.Context, keys ...string) context.Context {
 	return ctx
 }
 
+// WaitFunc registers a waiter on the concurrent aggregator and returns a function
+// marking it done. Aggregate blocks until every waiter is done. Without keys it
+// targets the keyless aggregator that can be waited on, and panics if keyless
+// concurrent aggregators of several types are registered since it cannot tell
+// which one to wait on; use WaitFuncOf to target the keyless aggregator of a
+// specific type.
 func WaitFunc(ctx context.Context, keys ...string) (context.Context, func()) {
-	ctxKey := buildContextKey(keys...)
-	aggVal := ctx.Value(ctxKey)
-	if aggVal == nil {
-		return ctx, func() {}
+	if reg := registryFrom(ctx); reg != nil && len(keys) == 0 && len(reg.waitable) > 0 {
+		if len(reg.waitable) > 1 {
+			panic("aggregator: WaitFunc without keys is ambiguous with keyless concurrent aggregators of several types, use WaitFuncOf")
+		}
+
+		return waitOn(ctx, reg.waitable[0].agg)
 	}
+
+	return waitFunc(ctx, buildContextKey(keys...))
+}
+
+// WaitFuncOf is WaitFunc for the aggregator of type T
+func WaitFuncOf[T any](ctx context.Context, keys ...string) (context.Context, func()) {
+	return waitFunc(ctx, typedContextKey[T](keys...))
+}
+
+func waitFunc(ctx context.Context, ctxKey any) (context.Context, func()) {
+	return waitOn(ctx, lookupValue(ctx, ctxKey))
+}
+
+// waitOn registers a waiter on aggVal if it is a concurrent aggregator
+func waitOn(ctx context.Context, aggVal any) (context.Context, func()) {
 	agg, ok := aggVal.(IConcurrentAggregator)
 	if !ok {
 		return ctx, func() {}
@@ -87,27 +111,107 @@ func WaitFunc(ctx context.Context, keys ...string) (context.Context, func()) {
 }
 
 func (a *concurrentAggregator[T]) Collect(data T) {
+	_ = a.TryCollect(data)
+}
+
+func (a *concurrentAggregator[T]) TryCollect(data T) error {
+	return a.collectContext(context.Background(), data)
+}
+
+func (a *concurrentAggregator[T]) collectContext(ctx context.Context, data T) error {
 	a.m.Lock()
-	defer a.m.Unlock()
+	err := a.addWaiting(ctx, a.m, data)
+	a.m.Unlock()
+
+	return a.settle(data, err)
+}
+
+func (a *concurrentAggregator[T]) collectContextAny(ctx context.Context, data any) error {
+	return a.collectContext(ctx, data.(T))
+}
+
+func (a *concurrentAggregator[T]) collectMany(ctx context.Context, items []T) error {
+	a.m.Lock()
+	n, err := a.addManyWaiting(ctx, a.m, items)
+	a.m.Unlock()
 
-	a.datas = append(a.datas, data)
+	return a.settleMany(items, n, err)
 }
 
 func (a *concurrentAggregator[T]) Aggregate() []T {
 	// Always call Wait before lock mutex for not cause deadlock
 	// between syncgroup and mutex
-	a.wg.Wait()
+	a.await(a.m, a.wg, &a.waiters)
+	defer a.m.Unlock()
 
-	a.m.Lock()
+	return a.snapshot()
+}
+
+func (a *concurrentAggregator[T]) AggregateUnsafe() []T {
+	a.await(a.m, a.wg, &a.waiters)
 	defer a.m.Unlock()
 
 	return a.datas
 }
 
+func (a *concurrentAggregator[T]) view() []T {
+	a.await(a.m, a.wg, &a.waiters)
+	defer a.m.Unlock()
+
+	return a.store.view()
+}
+
+func (a *concurrentAggregator[T]) AggregateWithDropped() ([]T, int) {
+	a.await(a.m, a.wg, &a.waiters)
+	defer a.m.Unlock()
+
+	return a.snapshot(), a.dropped
+}
+
+func (a *concurrentAggregator[T]) Drain() []T {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	return a.drain()
+}
+
+func (a *concurrentAggregator[T]) Seal() {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	a.seal()
+}
+
+func (a *concurrentAggregator[T]) AggregateAndSeal() []T {
+	a.await(a.m, a.wg, &a.waiters)
+	defer a.m.Unlock()
+
+	a.seal()
+	return a.snapshot()
+}
+
+func (a *concurrentAggregator[T]) LateCollects() int {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	return a.late
+}
+
 func (a *concurrentAggregator[T]) AddWait() {
+	a.waiters.Add(1)
 	a.wg.Add(1)
 }
 
 func (a *concurrentAggregator[T]) Done() {
+	a.waiters.Add(-1)
 	a.wg.Done()
 }
+
+func (a *concurrentAggregator[T]) inspect() AggregatorInfo {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	info := a.info(KindConcurrent)
+	info.Waiters = int(a.waiters.Load())
+	return info
+}
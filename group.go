@@ -0,0 +1,242 @@
+package aggregator
+
+import (
+	"context"
+	"slices"
+)
+
+var _ GroupedAggregator[any, int] = new(groupAggregator[any, int])
+var _ SealableAggregator[any] = new(groupAggregator[any, int])
+var _ BoundedAggregator[any] = new(groupAggregator[any, int])
+var _ inspector = new(groupAggregator[any, int])
+var _ ConcurrentContextAggregator[any] = new(concurrentGroupAggregator[any, int])
+var _ GroupedAggregator[any, int] = new(concurrentGroupAggregator[any, int])
+var _ SealableAggregator[any] = new(concurrentGroupAggregator[any, int])
+var _ BoundedAggregator[any] = new(concurrentGroupAggregator[any, int])
+var _ inspector = new(concurrentGroupAggregator[any, int])
+
+// GroupedAggregator is implemented by aggregators grouping items by key as they
+// are collected
+type GroupedAggregator[T any, K comparable] interface {
+	ContextAggregator[T]
+	// AggregateGrouped returns a snapshot of the items of every group
+	AggregateGrouped() map[K][]T
+	// AggregateGroupCounts returns the number of items of every group
+	AggregateGroupCounts() map[K]int
+}
+
+// AggregateGroupBy aggregates items into groups by the key returned by keyFn.
+// Items keep their collect order within a group.
+func AggregateGroupBy[T any, K comparable](ctx context.Context, keyFn func(T) K, keys ...string) (map[K][]T, error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupItems(agg.Aggregate(), keyFn, func(item T) T { return item }), nil
+}
+
+// AggregateGroupByWithTransform groups and transforms items in a single pass
+func AggregateGroupByWithTransform[T any, K comparable, R any](ctx context.Context, keyFn func(T) K, transform TransformFunc[T, R], keys ...string) (map[K][]R, error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupItems(agg.Aggregate(), keyFn, transform), nil
+}
+
+// AggregateCountBy counts items per key returned by keyFn
+func AggregateCountBy[T any, K comparable](ctx context.Context, keyFn func(T) K, keys ...string) (map[K]int, error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[K]int)
+	for _, item := range agg.Aggregate() {
+		counts[keyFn(item)]++
+	}
+
+	return counts, nil
+}
+
+// RegisterGroupAggregator registers an aggregator grouping items by the key
+// returned by keyFn as they are collected, so AggregateGrouped needs no extra
+// pass. Aggregate returns the items group by group, in the order each group
+// was first collected.
+//
+// WithConcurrency, WithKey and WithLateCollectHook are honored, other options
+// are ignored.
+func RegisterGroupAggregator[T any, K comparable](ctx context.Context, keyFn func(T) K, opts ...Option) context.Context {
+	cfg := newConfig(opts...)
+	return registerAggregator(ctx, typedContextKey[T](cfg.keys...), newGroupAggregator(keyFn, cfg))
+}
+
+// NewGroup creates a group aggregator without registering it into a context
+func NewGroup[T any, K comparable](keyFn func(T) K, opts ...Option) ContextAggregator[T] {
+	return newGroupAggregator(keyFn, newConfig(opts...))
+}
+
+// AggregateGrouped aggregates the groups of the group aggregator of type T with
+// keys of type K. It returns ErrNotGrouped if the aggregator does not group its
+// items by K.
+func AggregateGrouped[T any, K comparable](ctx context.Context, keys ...string) (map[K][]T, error) {
+	grouped, err := extractGrouped[T, K](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return grouped.AggregateGrouped(), nil
+}
+
+// AggregateGroupCounts returns the number of items of every group of the group
+// aggregator of type T with keys of type K
+func AggregateGroupCounts[T any, K comparable](ctx context.Context, keys ...string) (map[K]int, error) {
+	grouped, err := extractGrouped[T, K](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return grouped.AggregateGroupCounts(), nil
+}
+
+func extractGrouped[T any, K comparable](ctx context.Context, keys ...string) (GroupedAggregator[T, K], error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped, ok := agg.(GroupedAggregator[T, K])
+	if !ok {
+		return nil, ErrNotGrouped
+	}
+
+	return grouped, nil
+}
+
+func groupItems[T any, K comparable, R any](items []T, keyFn func(T) K, transform TransformFunc[T, R]) map[K][]R {
+	groups := make(map[K][]R)
+	for _, item := range items {
+		key := keyFn(item)
+		groups[key] = append(groups[key], transform(item))
+	}
+
+	return groups
+}
+
+func newGroupAggregator[T any, K comparable](keyFn func(T) K, cfg *config) ContextAggregator[T] {
+	g := &grouping[T, K]{
+		keyFn:  keyFn,
+		groups: make(map[K][]T),
+	}
+	if cfg.concurrent {
+		agg := &concurrentGroupAggregator[T, K]{}
+		agg.init(g, KindConcurrentGroup, cfg)
+		return agg
+	}
+
+	agg := &groupAggregator[T, K]{}
+	agg.init(g, KindGroup, cfg)
+	return agg
+}
+
+// grouping is the collection kept by the group aggregators
+type grouping[T any, K comparable] struct {
+	keyFn  func(T) K
+	groups map[K][]T
+	// order lists the keys in the order their group was first collected
+	order []K
+	count int
+}
+
+func (g *grouping[T, K]) add(data T) {
+	key := g.keyFn(data)
+	group, ok := g.groups[key]
+	if !ok {
+		g.order = append(g.order, key)
+	}
+	g.groups[key] = append(group, data)
+	g.count++
+}
+
+// snapshot returns the items group by group
+func (g *grouping[T, K]) snapshot() []T {
+	items := make([]T, 0, g.count)
+	for _, key := range g.order {
+		items = append(items, g.groups[key]...)
+	}
+
+	return items
+}
+
+func (g *grouping[T, K]) grouped() map[K][]T {
+	grouped := make(map[K][]T, len(g.groups))
+	for key, group := range g.groups {
+		grouped[key] = slices.Clone(group)
+	}
+
+	return grouped
+}
+
+func (g *grouping[T, K]) counts() map[K]int {
+	counts := make(map[K]int, len(g.groups))
+	for key, group := range g.groups {
+		counts[key] = len(group)
+	}
+
+	return counts
+}
+
+func (g *grouping[T, K]) drain() []T {
+	items := g.snapshot()
+	clear(g.groups)
+	g.order = nil
+	g.count = 0
+
+	return items
+}
+
+// dropped returns zero, groups keep every item
+func (g *grouping[T, K]) dropped() int {
+	return 0
+}
+
+func (g *grouping[T, K]) info() AggregatorInfo {
+	return AggregatorInfo{
+		Len: g.count,
+		Cap: -1,
+	}
+}
+
+// groupAggregator is a sequential group aggregator
+type groupAggregator[T any, K comparable] struct {
+	sequentialBounded[T, *grouping[T, K]]
+}
+
+func (a *groupAggregator[T, K]) AggregateGrouped() map[K][]T {
+	return a.items.grouped()
+}
+
+func (a *groupAggregator[T, K]) AggregateGroupCounts() map[K]int {
+	return a.items.counts()
+}
+
+// concurrentGroupAggregator is a thread-safe group aggregator
+type concurrentGroupAggregator[T any, K comparable] struct {
+	synchronizedBounded[T, *grouping[T, K]]
+}
+
+func (a *concurrentGroupAggregator[T, K]) AggregateGrouped() map[K][]T {
+	a.lockAfterWait()
+	defer a.m.Unlock()
+
+	return a.items.grouped()
+}
+
+func (a *concurrentGroupAggregator[T, K]) AggregateGroupCounts() map[K]int {
+	a.lockAfterWait()
+	defer a.m.Unlock()
+
+	return a.items.counts()
+}
@@ -0,0 +1,63 @@
+package aggregator
+
+import (
+	"context"
+	"iter"
+)
+
+// Handle is a resolved reference to an aggregator. Collecting through a handle
+// is a direct method call that skips building the context key and looking the
+// aggregator up, which matters in tight loops. A handle behaves exactly like the
+// aggregator it refers to, whatever its flavor.
+//
+// The zero Handle refers to no aggregator and must not be used.
+type Handle[T any] struct {
+	ctx context.Context
+	agg ContextAggregator[T]
+}
+
+// Lookup resolves the aggregator of type T registered under keys
+func Lookup[T any](ctx context.Context, keys ...string) (Handle[T], error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return Handle[T]{}, err
+	}
+
+	return Handle[T]{ctx: ctx, agg: agg}, nil
+}
+
+// LookupKey resolves the aggregator registered under key
+func LookupKey[T any](ctx context.Context, key *Key[T]) (Handle[T], error) {
+	agg, err := lookupAggregator[T](ctx, key)
+	if err != nil {
+		return Handle[T]{}, err
+	}
+
+	return Handle[T]{ctx: ctx, agg: agg}, nil
+}
+
+// Collect collects data into the aggregator. If the aggregator blocks on
+// overflow, Collect stops waiting once the context given to Lookup is done.
+func (h Handle[T]) Collect(data T) error {
+	return collectInto(h.ctx, h.agg, data)
+}
+
+// CollectMany is the handle counterpart of the CollectMany function
+func (h Handle[T]) CollectMany(items []T) error {
+	return collectManyInto(h.ctx, h.agg, items)
+}
+
+// Aggregate aggregates data from the aggregator
+func (h Handle[T]) Aggregate() []T {
+	return h.agg.Aggregate()
+}
+
+// All is the handle counterpart of the All function
+func (h Handle[T]) All() iter.Seq[T] {
+	return all(h.agg)
+}
+
+// AggregateUnsafe is the handle counterpart of the AggregateUnsafe function
+func (h Handle[T]) AggregateUnsafe() []T {
+	return aggregateUnsafe(h.agg)
+}
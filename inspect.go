@@ -0,0 +1,165 @@
+package aggregator
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Kind is the flavor of an aggregator
+type Kind string
+
+const (
+	KindBase                Kind = "base"
+	KindConcurrent          Kind = "concurrent"
+	KindStreaming           Kind = "streaming"
+	KindConcurrentStreaming Kind = "concurrent-streaming"
+	KindAsyncStreaming      Kind = "async-streaming"
+	KindSharded             Kind = "sharded"
+	KindRing                Kind = "ring"
+	KindConcurrentRing      Kind = "concurrent-ring"
+	KindReservoir           Kind = "reservoir"
+	KindConcurrentReservoir Kind = "concurrent-reservoir"
+	KindSet                 Kind = "set"
+	KindConcurrentSet       Kind = "concurrent-set"
+	KindGroup               Kind = "group"
+	KindConcurrentGroup     Kind = "concurrent-group"
+	KindReducer             Kind = "reducer"
+	KindConcurrentReducer   Kind = "concurrent-reducer"
+	KindStats               Kind = "stats"
+	KindConcurrentStats     Kind = "concurrent-stats"
+	KindTopK                Kind = "top-k"
+	KindConcurrentTopK      Kind = "concurrent-top-k"
+	// KindCustom is the kind of aggregators registered with RegisterAggregator
+	KindCustom Kind = "custom"
+)
+
+// AggregatorInfo describes an aggregator registered in a context
+type AggregatorInfo struct {
+	// Key is the key the aggregator is registered under: the string keys joined
+	// with "_", the name of a typed Key, or empty for a keyless aggregator
+	Key string
+	// ElemType is the name of the element type
+	ElemType string
+	Kind     Kind
+	// Len is the number of collected items, or -1 if unknown
+	Len int
+	// Cap is the capacity of the underlying storage, or -1 if unknown
+	Cap int
+	// Waiters is the number of outstanding AddWait calls not yet matched by Done
+	Waiters int
+	// Sealed reports whether the aggregator rejects new items
+	Sealed bool
+	// LateCollects is the number of items rejected since sealing
+	LateCollects int
+	// MaxItems is the limit set by WithMaxItems, or zero if unbounded
+	MaxItems int
+	// Dropped is the number of items discarded because of the limit
+	Dropped int
+	// SkippedCallbacks is the number of items whose asynchronous callbacks were
+	// skipped by the queue policy or given up by their collector, the items
+	// being stored. A skipped CollectMany batch counts all of its items.
+	SkippedCallbacks int
+}
+
+// String formats the info on a single line, e.g. for log dumps
+func (i AggregatorInfo) String() string {
+	return fmt.Sprintf("key=%q type=%s kind=%s len=%d cap=%d waiters=%d",
+		i.Key, i.ElemType, i.Kind, i.Len, i.Cap, i.Waiters)
+}
+
+// inspector is implemented by the library's aggregators to describe themselves.
+// Key is filled in by Inspect.
+type inspector interface {
+	inspect() AggregatorInfo
+}
+
+// keyNamer is implemented by context keys that are not plain strings
+type keyNamer interface {
+	keyName() string
+}
+
+func (defaultContextKey[T]) keyName() string {
+	return ""
+}
+
+func (k *Key[T]) keyName() string {
+	return k.name
+}
+
+// Inspect lists the aggregators registered in the context, sorted by key and
+// element type. Inspecting never blocks on outstanding waiters.
+func Inspect(ctx context.Context) []AggregatorInfo {
+	reg := registryFrom(ctx)
+	if reg == nil {
+		return nil
+	}
+
+	var infos []AggregatorInfo
+	for ctxKey, agg := range reg.all() {
+		if ctxKey == contextAggregatorContextKey {
+			// Alias of the latest keyless aggregator, already listed under its own key
+			continue
+		}
+
+		info := inspectAggregator(agg)
+		info.Key = inspectKey(ctxKey)
+		infos = append(infos, info)
+	}
+
+	slices.SortFunc(infos, func(a, b AggregatorInfo) int {
+		return cmp.Or(cmp.Compare(a.Key, b.Key), cmp.Compare(a.ElemType, b.ElemType))
+	})
+
+	return infos
+}
+
+// Describe formats the aggregators registered in the context as a table, for
+// test failures and log dumps
+func Describe(ctx context.Context) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tTYPE\tKIND\tLEN\tCAP\tWAITERS")
+	for _, info := range Inspect(ctx) {
+		key := info.Key
+		if key == "" {
+			key = "<default>"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\n", key, info.ElemType, info.Kind, info.Len, info.Cap, info.Waiters)
+	}
+	_ = w.Flush()
+
+	return sb.String()
+}
+
+func inspectAggregator(agg any) AggregatorInfo {
+	if i, ok := agg.(inspector); ok {
+		return i.inspect()
+	}
+
+	info := AggregatorInfo{Kind: KindCustom, Len: -1, Cap: -1}
+	if collect := reflect.ValueOf(agg).MethodByName("Collect"); collect.IsValid() && collect.Type().NumIn() == 1 {
+		info.ElemType = collect.Type().In(0).String()
+	}
+
+	return info
+}
+
+func inspectKey(ctxKey any) string {
+	switch k := ctxKey.(type) {
+	case contextKey:
+		return strings.TrimPrefix(string(k), string(contextAggregatorContextKey)+"_")
+	case keyNamer:
+		return k.keyName()
+	default:
+		return fmt.Sprint(k)
+	}
+}
+
+func elemTypeName[T any]() string {
+	return reflect.TypeFor[T]().String()
+}
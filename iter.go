@@ -0,0 +1,162 @@
+package aggregator
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns a lazy sequence over the items of the aggregator of type T, to be
+// composed with Filter, Map, Take, Skip, Distinct and Chunk without building an
+// intermediate slice at every step. The items are snapshotted when iteration
+// starts, so ranging over the sequence again sees the items collected since.
+// The slice-backed aggregators do not even copy them: their items are only
+// copied if collecting overwrites them during iteration.
+//
+// All yields nothing if there is no such aggregator; use Lookup and Handle.All
+// to tell a missing aggregator apart from an empty one.
+func All[T any](ctx context.Context, keys ...string) iter.Seq[T] {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return func(func(T) bool) {}
+	}
+
+	return all(agg)
+}
+
+// AllKey is All for an aggregator registered under key
+func AllKey[T any](ctx context.Context, key *Key[T]) iter.Seq[T] {
+	agg, err := lookupAggregator[T](ctx, key)
+	if err != nil {
+		return func(func(T) bool) {}
+	}
+
+	return all(agg)
+}
+
+// viewer is implemented by aggregators that can hand out their items without
+// copying them, unaffected by later collects
+type viewer[T any] interface {
+	view() []T
+}
+
+func all[T any](agg ContextAggregator[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, item := range viewOf(agg) {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// viewOf returns the items of agg without copying them if it is a viewer, or a
+// snapshot otherwise. The items must not be modified.
+func viewOf[T any](agg ContextAggregator[T]) []T {
+	if v, ok := agg.(viewer[T]); ok {
+		return v.view()
+	}
+
+	return agg.Aggregate()
+}
+
+// Filter yields the items of seq that match the filter predicate
+func Filter[T any](seq iter.Seq[T], filter FilterFunc[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for item := range seq {
+			if filter(item) && !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// Map yields the items of seq transformed from type T to type R
+func Map[T any, R any](seq iter.Seq[T], transform TransformFunc[T, R]) iter.Seq[R] {
+	return func(yield func(R) bool) {
+		for item := range seq {
+			if !yield(transform(item)) {
+				return
+			}
+		}
+	}
+}
+
+// Take yields the first n items of seq, and stops pulling from seq afterwards
+func Take[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+
+		taken := 0
+		for item := range seq {
+			if !yield(item) {
+				return
+			}
+			taken++
+			if taken == n {
+				return
+			}
+		}
+	}
+}
+
+// Skip yields the items of seq after the first n
+func Skip[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		skipped := 0
+		for item := range seq {
+			if skipped < n {
+				skipped++
+				continue
+			}
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// Distinct yields the first occurrence of every item of seq. It remembers the
+// items seen, so its memory grows with the number of distinct items.
+func Distinct[T comparable](seq iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		seen := make(map[T]struct{})
+		for item := range seq {
+			if _, ok := seen[item]; ok {
+				continue
+			}
+			seen[item] = struct{}{}
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// Chunk yields the items of seq in slices of size items, the last one possibly
+// shorter. Every chunk is a new slice the caller owns. Chunk panics if size is
+// not positive.
+func Chunk[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	if size <= 0 {
+		panic("aggregator: chunk size must be positive")
+	}
+
+	return func(yield func([]T) bool) {
+		chunk := make([]T, 0, size)
+		for item := range seq {
+			chunk = append(chunk, item)
+			if len(chunk) < size {
+				continue
+			}
+			if !yield(chunk) {
+				return
+			}
+			chunk = make([]T, 0, size)
+		}
+
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
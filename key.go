@@ -0,0 +1,87 @@
+package aggregator
+
+import (
+	"context"
+)
+
+// Key is a typed handle identifying an aggregator in a context. Keys are
+// compared by identity, so two keys created with the same name never collide,
+// and the element type is carried by the key so using it with the wrong type
+// fails to compile.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey creates a new unique key for an aggregator of type T. The name is only
+// used for display.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// String returns the name the key was created with.
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+// RegisterKey registers an aggregator built from the given options into context
+// under a typed key. WithKey options are ignored since the key is given explicitly.
+// RegisterKey panics if key is nil.
+func RegisterKey[T any](ctx context.Context, key *Key[T], opts ...Option) context.Context {
+	if key == nil {
+		panic("aggregator: nil key")
+	}
+
+	cfg := newConfig(opts...)
+	return registerAggregator(ctx, key, newAggregator[T](ctx, cfg))
+}
+
+// CollectKey collects data into the aggregator registered under key
+func CollectKey[T any](ctx context.Context, key *Key[T], data T) error {
+	agg, err := lookupAggregator[T](ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return collectInto(ctx, agg, data)
+}
+
+// AggregateKey aggregates data from the aggregator registered under key
+func AggregateKey[T any](ctx context.Context, key *Key[T]) ([]T, error) {
+	agg, err := lookupAggregator[T](ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return agg.Aggregate(), nil
+}
+
+// AggregateWithFilterKey is AggregateWithFilter for an aggregator registered under key
+func AggregateWithFilterKey[T any](ctx context.Context, key *Key[T], filter FilterFunc[T]) ([]T, error) {
+	agg, err := lookupAggregator[T](ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterItems(viewOf(agg), filter), nil
+}
+
+// AggregateWithTransformKey is AggregateWithTransform for an aggregator registered under key
+func AggregateWithTransformKey[T any, R any](ctx context.Context, key *Key[T], transform TransformFunc[T, R]) ([]R, error) {
+	agg, err := lookupAggregator[T](ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return transformItems(viewOf(agg), transform), nil
+}
+
+// AggregateWithFilterAndTransformKey is AggregateWithFilterAndTransform for an
+// aggregator registered under key
+func AggregateWithFilterAndTransformKey[T any, R any](ctx context.Context, key *Key[T], filter FilterFunc[T], transform TransformFunc[T, R]) ([]R, error) {
+	agg, err := lookupAggregator[T](ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterAndTransformItems(viewOf(agg), filter, transform), nil
+}
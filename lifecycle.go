@@ -0,0 +1,144 @@
+package aggregator
+
+import (
+	"context"
+)
+
+// LateCollectHook is called with every item collected after its aggregator was sealed
+type LateCollectHook[T any] func(T)
+
+// FallibleAggregator is implemented by aggregators whose collection can fail,
+// e.g. after being sealed. Collect, CollectKey and Handle.Collect report the
+// error of TryCollect; the Collect method silently drops the item instead.
+type FallibleAggregator[T any] interface {
+	ContextAggregator[T]
+	TryCollect(data T) error
+}
+
+// SealableAggregator is implemented by aggregators with a lifecycle. Once sealed,
+// an aggregator rejects every Collect with ErrAggregatorClosed and counts it as
+// a late collect. All the built-in aggregators are sealable.
+type SealableAggregator[T any] interface {
+	FallibleAggregator[T]
+	// Seal rejects every later collect
+	Seal()
+	// AggregateAndSeal seals the aggregator and returns a snapshot of everything
+	// collected before. Concurrent aggregators wait for outstanding waiters first.
+	AggregateAndSeal() []T
+	// LateCollects returns the number of items rejected since sealing
+	LateCollects() int
+}
+
+// WithLateCollectHook sets a hook called with every item collected after the
+// aggregator was sealed, to report stragglers. The hook element type must match
+// the type parameter given to Register.
+func WithLateCollectHook[T any](hook LateCollectHook[T]) Option {
+	return func(c *config) {
+		c.lateHook = hook
+	}
+}
+
+// Seal seals the aggregator of type T, so every later Collect returns
+// ErrAggregatorClosed
+func Seal[T any](ctx context.Context, keys ...string) error {
+	agg, err := extractSealable[T](ctx, keys...)
+	if err != nil {
+		return err
+	}
+
+	agg.Seal()
+	return nil
+}
+
+// AggregateAndSeal seals the aggregator of type T and returns everything
+// collected before, so nothing collected afterwards is lost silently
+func AggregateAndSeal[T any](ctx context.Context, keys ...string) ([]T, error) {
+	agg, err := extractSealable[T](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return agg.AggregateAndSeal(), nil
+}
+
+// LateCollects returns the number of items rejected by the aggregator of type T
+// since it was sealed
+func LateCollects[T any](ctx context.Context, keys ...string) (int, error) {
+	agg, err := extractSealable[T](ctx, keys...)
+	if err != nil {
+		return 0, err
+	}
+
+	return agg.LateCollects(), nil
+}
+
+func extractSealable[T any](ctx context.Context, keys ...string) (SealableAggregator[T], error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	sealable, ok := agg.(SealableAggregator[T])
+	if !ok {
+		return nil, ErrNotSealable
+	}
+
+	return sealable, nil
+}
+
+// collectInto collects data into agg, reporting the error of fallible aggregators.
+// Blocking aggregators stop waiting once ctx is done.
+func collectInto[T any](ctx context.Context, agg ContextAggregator[T], data T) error {
+	if blocking, ok := agg.(contextCollector[T]); ok {
+		return blocking.collectContext(ctx, data)
+	}
+
+	if fallible, ok := agg.(FallibleAggregator[T]); ok {
+		return fallible.TryCollect(data)
+	}
+
+	agg.Collect(data)
+	return nil
+}
+
+// lifecycle is the sealing state shared by the special aggregators. It is not
+// synchronized; concurrent aggregators guard it with their mutex.
+type lifecycle[T any] struct {
+	sealed bool
+	late   int
+	onLate LateCollectHook[T]
+}
+
+func newLifecycle[T any](cfg *config) lifecycle[T] {
+	return lifecycle[T]{onLate: typedOption[LateCollectHook[T]](cfg.lateHook, "late collect hook")}
+}
+
+// reject counts n late collects and returns ErrAggregatorClosed once sealed
+func (l *lifecycle[T]) reject(n int) error {
+	if !l.sealed {
+		return nil
+	}
+
+	l.late += n
+	return ErrAggregatorClosed
+}
+
+// settle reports late items to the hook, outside of any lock
+func (l *lifecycle[T]) settle(data T, err error) error {
+	if err == ErrAggregatorClosed && l.onLate != nil {
+		invokeCallback(CollectCallback[T](l.onLate), data)
+	}
+
+	return err
+}
+
+// settleMany is settle for a batch
+func (l *lifecycle[T]) settleMany(items []T, err error) error {
+	if err == ErrAggregatorClosed && l.onLate != nil {
+		for _, item := range items {
+			invokeCallback(CollectCallback[T](l.onLate), item)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,91 @@
+package aggregator
+
+import (
+	"fmt"
+)
+
+// Option configures an aggregator created by Register. Options can be combined
+// in any order; when the same option is given more than once the last one wins.
+type Option func(*config)
+
+// config holds the settings collected from a list of Option.
+type config struct {
+	keys       []string
+	capacity   int
+	concurrent bool
+	callback   any
+	lateHook   any
+	maxItems   int
+	policy     OverflowPolicy
+	seed       uint64
+	seeded     bool
+	duplicates DuplicatePolicy
+	merge      any
+	accuracy   float64
+	sharded    bool
+	shards     int
+	batch      any
+
+	async       bool
+	workers     int
+	queueSize   int
+	queuePolicy OverflowPolicy
+	orderKey    any
+}
+
+func newConfig(opts ...Option) *config {
+	cfg := &config{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+
+	return cfg
+}
+
+// WithKey sets the keys used to store the aggregator in the context. Use
+// different keys to keep many aggregators of the same type in one context.
+func WithKey(keys ...string) Option {
+	return func(c *config) {
+		c.keys = keys
+	}
+}
+
+// WithCapacity sets a capacity hint for pre-allocating the underlying storage.
+func WithCapacity(capacity int) Option {
+	return func(c *config) {
+		c.capacity = capacity
+	}
+}
+
+// WithConcurrency makes the aggregator safe for collecting from multiple goroutines.
+func WithConcurrency() Option {
+	return func(c *config) {
+		c.concurrent = true
+	}
+}
+
+// WithCallback sets a callback invoked synchronously for every collected item.
+// The callback element type must match the type parameter given to Register.
+func WithCallback[T any](callback CollectCallback[T]) Option {
+	return func(c *config) {
+		c.callback = callback
+	}
+}
+
+// typedOption converts an option value stored as any back to its typed form.
+// It panics if the option was given for another element type.
+func typedOption[F any](value any, name string) F {
+	var typed F
+	if value == nil {
+		return typed
+	}
+
+	typed, ok := value.(F)
+	if !ok {
+		panic(fmt.Sprintf("aggregator: %s of type %T does not match %T", name, value, typed))
+	}
+
+	return typed
+}
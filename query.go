@@ -0,0 +1,181 @@
+package aggregator
+
+import (
+	"context"
+	"iter"
+	"slices"
+)
+
+// QueryBuilder is a query over the items of an aggregator, built by chaining
+// Where, OrderBy, Offset and Limit and run by a terminal operation: ToSlice,
+// Count, First, Last, or the GroupBy and Reduce functions. Steps apply in that
+// order whatever order they are chained in, as in SQL.
+//
+// A QueryBuilder is a value: every step returns a new query and leaves the one
+// it was called on unchanged, so a base query can be shared and refined. The
+// aggregator is looked up once by Query, and its items are snapshotted by every
+// terminal operation.
+type QueryBuilder[T any] struct {
+	agg     ContextAggregator[T]
+	err     error
+	filters []FilterFunc[T]
+	orders  []func(a, b T) int
+	offset  int
+	// limit is negative when there is none
+	limit int
+}
+
+// Query starts a query over the items of the aggregator of type T. A missing or
+// mistyped aggregator is reported by the terminal operation with
+// ErrNotFoundAggregator or ErrInvalidType.
+func Query[T any](ctx context.Context, keys ...string) QueryBuilder[T] {
+	agg, err := extractAggregator[T](ctx, keys...)
+	return QueryBuilder[T]{agg: agg, err: err, limit: -1}
+}
+
+// QueryKey is Query for an aggregator registered under key
+func QueryKey[T any](ctx context.Context, key *Key[T]) QueryBuilder[T] {
+	agg, err := lookupAggregator[T](ctx, key)
+	return QueryBuilder[T]{agg: agg, err: err, limit: -1}
+}
+
+// Where keeps only the items matching filter. Several Where steps must all match.
+func (q QueryBuilder[T]) Where(filter FilterFunc[T]) QueryBuilder[T] {
+	q.filters = append(slices.Clip(q.filters), filter)
+	return q
+}
+
+// OrderBy sorts the items by cmp, which returns a negative number when a comes
+// before b, like cmp.Compare. Further OrderBy steps break the ties of the
+// previous ones. The sort is stable, so equal items keep their collect order.
+func (q QueryBuilder[T]) OrderBy(cmp func(a, b T) int) QueryBuilder[T] {
+	q.orders = append(slices.Clip(q.orders), cmp)
+	return q
+}
+
+// Offset skips the first n items
+func (q QueryBuilder[T]) Offset(n int) QueryBuilder[T] {
+	q.offset = max(n, 0)
+	return q
+}
+
+// Limit keeps at most n items. A negative n removes the limit.
+func (q QueryBuilder[T]) Limit(n int) QueryBuilder[T] {
+	q.limit = max(n, -1)
+	return q
+}
+
+// ToSlice runs the query and returns its items
+func (q QueryBuilder[T]) ToSlice() ([]T, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	return slices.AppendSeq([]T{}, q.seq()), nil
+}
+
+// Count runs the query and returns the number of its items
+func (q QueryBuilder[T]) Count() (int, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+
+	n := 0
+	for range q.seq() {
+		n++
+	}
+
+	return n, nil
+}
+
+// First runs the query and returns its first item, and false if it has none
+func (q QueryBuilder[T]) First() (T, bool, error) {
+	var zero T
+	if q.err != nil {
+		return zero, false, q.err
+	}
+
+	for item := range q.seq() {
+		return item, true, nil
+	}
+
+	return zero, false, nil
+}
+
+// Last runs the query and returns its last item, and false if it has none
+func (q QueryBuilder[T]) Last() (T, bool, error) {
+	var last T
+	if q.err != nil {
+		return last, false, q.err
+	}
+
+	found := false
+	for item := range q.seq() {
+		last, found = item, true
+	}
+
+	return last, found, nil
+}
+
+// GroupBy runs the query and groups its items by the key returned by keyFn.
+// Items keep the query order within a group. It is a function rather than a
+// method since methods cannot have type parameters.
+func GroupBy[T any, K comparable](q QueryBuilder[T], keyFn func(T) K) (map[K][]T, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	groups := make(map[K][]T)
+	for item := range q.seq() {
+		key := keyFn(item)
+		groups[key] = append(groups[key], item)
+	}
+
+	return groups, nil
+}
+
+// Reduce runs the query and folds its items into a state starting from init
+func Reduce[T any, S any](q QueryBuilder[T], init S, reduce ReduceFunc[S, T]) (S, error) {
+	if q.err != nil {
+		return init, q.err
+	}
+
+	state := init
+	for item := range q.seq() {
+		state = reduce(state, item)
+	}
+
+	return state, nil
+}
+
+// seq returns the items of the query. Only ordering needs the filtered items
+// in a slice; everything else is streamed.
+func (q QueryBuilder[T]) seq() iter.Seq[T] {
+	seq := all(q.agg)
+	for _, filter := range q.filters {
+		seq = Filter(seq, filter)
+	}
+
+	if len(q.orders) > 0 {
+		items := slices.AppendSeq([]T{}, seq)
+		slices.SortStableFunc(items, q.compare)
+		seq = slices.Values(items)
+	}
+
+	seq = Skip(seq, q.offset)
+	if q.limit >= 0 {
+		seq = Take(seq, q.limit)
+	}
+
+	return seq
+}
+
+func (q QueryBuilder[T]) compare(a, b T) int {
+	for _, cmp := range q.orders {
+		if c := cmp(a, b); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
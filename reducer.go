@@ -0,0 +1,238 @@
+package aggregator
+
+import (
+	"context"
+)
+
+var _ Reducer[any] = new(reducerAggregator[any, any])
+var _ SealableAggregator[any] = new(reducerAggregator[any, any])
+var _ inspector = new(reducerAggregator[any, any])
+var _ ConcurrentContextAggregator[any] = new(concurrentReducerAggregator[any, any])
+var _ Reducer[any] = new(concurrentReducerAggregator[any, any])
+var _ SealableAggregator[any] = new(concurrentReducerAggregator[any, any])
+var _ inspector = new(concurrentReducerAggregator[any, any])
+
+// ReduceFunc folds an item into the state of a reducer
+type ReduceFunc[S any, T any] func(state S, item T) S
+
+// MergeFunc combines two partial states of a reducer
+type MergeFunc[S any] func(a, b S) S
+
+// Reducer is implemented by aggregators folding items into a state of type S
+// as they are collected, instead of keeping them
+type Reducer[S any] interface {
+	// AggregateReduced returns the current state
+	AggregateReduced() S
+}
+
+// WithMerge sets the function combining partial states of a reducer, which
+// enables NewPartial. The state type must match the one given to RegisterReducer.
+func WithMerge[S any](merge MergeFunc[S]) Option {
+	return func(c *config) {
+		c.merge = merge
+	}
+}
+
+// RegisterReducer registers an aggregator keeping only the running result of
+// reduce over the collected items, starting from init, e.g. a counter, a sum or
+// a min/max. It uses constant memory; Aggregate returns nil since items are not
+// kept, use AggregateReduced to read the state.
+//
+// init is reused by partials, so when S holds references reduce and merge should
+// return new states rather than modify the ones they are given.
+//
+// WithConcurrency, WithKey, WithMerge and WithLateCollectHook are honored, other
+// options are ignored. RegisterReducer panics if WithMerge was given a function
+// for a state type other than S.
+func RegisterReducer[T any, S any](ctx context.Context, init S, reduce ReduceFunc[S, T], opts ...Option) context.Context {
+	cfg := newConfig(opts...)
+	return registerAggregator(ctx, typedContextKey[T](cfg.keys...), newReducerAggregator(init, reduce, cfg))
+}
+
+// NewReducer creates a reducer aggregator without registering it into a context
+func NewReducer[T any, S any](init S, reduce ReduceFunc[S, T], opts ...Option) ContextAggregator[T] {
+	return newReducerAggregator(init, reduce, newConfig(opts...))
+}
+
+// AggregateReduced returns the state of the reducer with element type T and
+// state type S. It returns ErrInvalidType if the aggregator of type T is not a
+// reducer with state type S.
+func AggregateReduced[T any, S any](ctx context.Context, keys ...string) (S, error) {
+	var zero S
+
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return zero, err
+	}
+
+	reducer, ok := agg.(Reducer[S])
+	if !ok {
+		return zero, ErrInvalidType
+	}
+
+	return reducer.AggregateReduced(), nil
+}
+
+// Partial folds items into a state local to one goroutine, without locking, and
+// merges it into its reducer on Commit. It is not safe for concurrent use.
+type Partial[T any, S any] struct {
+	init   S
+	state  S
+	count  int
+	reduce ReduceFunc[S, T]
+	commit func(state S, count int) error
+}
+
+// NewPartial returns a Partial of the reducer with element type T and state
+// type S. It returns ErrNotMergeable if the reducer was registered without
+// WithMerge, and ErrInvalidType if the aggregator is not such a reducer.
+func NewPartial[T any, S any](ctx context.Context, keys ...string) (*Partial[T, S], error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	reducer, ok := agg.(partialReducer[T, S])
+	if !ok {
+		return nil, ErrInvalidType
+	}
+
+	return reducer.newPartial()
+}
+
+// Collect folds data into the partial state
+func (p *Partial[T, S]) Collect(data T) {
+	p.state = p.reduce(p.state, data)
+	p.count++
+}
+
+// Commit merges the partial state into the reducer and starts over from the
+// initial state. If the reducer was sealed, the partial state is discarded,
+// counted as late collects, and ErrAggregatorClosed is returned.
+func (p *Partial[T, S]) Commit() error {
+	if p.count == 0 {
+		return nil
+	}
+
+	err := p.commit(p.state, p.count)
+	p.state = p.init
+	p.count = 0
+
+	return err
+}
+
+// partialReducer is implemented by the reducer aggregators to create partials
+type partialReducer[T any, S any] interface {
+	newPartial() (*Partial[T, S], error)
+}
+
+func newReducerAggregator[T any, S any](init S, reduce ReduceFunc[S, T], cfg *config) ContextAggregator[T] {
+	r := &reduction[T, S]{
+		init:   init,
+		state:  init,
+		reduce: reduce,
+		merge:  typedOption[MergeFunc[S]](cfg.merge, "merge function"),
+	}
+	if cfg.concurrent {
+		agg := &concurrentReducerAggregator[T, S]{}
+		agg.init(r, KindConcurrentReducer, cfg)
+		return agg
+	}
+
+	agg := &reducerAggregator[T, S]{}
+	agg.init(r, KindReducer, cfg)
+	return agg
+}
+
+// reduction is the collection kept by the reducer aggregators: the state
+// rather than the items
+type reduction[T any, S any] struct {
+	init   S
+	state  S
+	reduce ReduceFunc[S, T]
+	merge  MergeFunc[S]
+	// count is the number of items folded into state
+	count int
+}
+
+func (r *reduction[T, S]) add(data T) {
+	r.state = r.reduce(r.state, data)
+	r.count++
+}
+
+// addPartial merges the state of a partial which folded count items
+func (r *reduction[T, S]) addPartial(state S, count int) {
+	r.state = r.merge(r.state, state)
+	r.count += count
+}
+
+// snapshot returns nil, since a reducer does not keep items
+func (r *reduction[T, S]) snapshot() []T {
+	return nil
+}
+
+func (r *reduction[T, S]) partial(commit func(S, int) error) (*Partial[T, S], error) {
+	if r.merge == nil {
+		return nil, ErrNotMergeable
+	}
+
+	return &Partial[T, S]{
+		init:   r.init,
+		state:  r.init,
+		reduce: r.reduce,
+		commit: commit,
+	}, nil
+}
+
+func (r *reduction[T, S]) info() AggregatorInfo {
+	return AggregatorInfo{
+		Len: r.count,
+		Cap: -1,
+	}
+}
+
+// reducerAggregator is a sequential reducer aggregator
+type reducerAggregator[T any, S any] struct {
+	sequential[T, *reduction[T, S]]
+}
+
+func (a *reducerAggregator[T, S]) AggregateReduced() S {
+	return a.items.state
+}
+
+func (a *reducerAggregator[T, S]) newPartial() (*Partial[T, S], error) {
+	return a.items.partial(func(state S, count int) error {
+		if err := a.reject(count); err != nil {
+			return err
+		}
+
+		a.items.addPartial(state, count)
+		return nil
+	})
+}
+
+// concurrentReducerAggregator is a thread-safe reducer aggregator
+type concurrentReducerAggregator[T any, S any] struct {
+	synchronized[T, *reduction[T, S]]
+}
+
+func (a *concurrentReducerAggregator[T, S]) AggregateReduced() S {
+	a.lockAfterWait()
+	defer a.m.Unlock()
+
+	return a.items.state
+}
+
+func (a *concurrentReducerAggregator[T, S]) newPartial() (*Partial[T, S], error) {
+	return a.items.partial(func(state S, count int) error {
+		a.m.Lock()
+		defer a.m.Unlock()
+
+		if err := a.reject(count); err != nil {
+			return err
+		}
+
+		a.items.addPartial(state, count)
+		return nil
+	})
+}
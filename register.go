@@ -0,0 +1,118 @@
+package aggregator
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// Register registers an aggregator into context, built from the given options.
+// Without options it behaves like RegisterBaseContextAggregator. Options compose
+// freely, e.g. WithConcurrency together with WithCallback gives a thread-safe
+// streaming aggregator.
+//
+// Register panics if WithCallback, WithBatchCallback, WithLateCollectHook or
+// WithOrderKey was given a function for a type other than T.
+func Register[T any](ctx context.Context, opts ...Option) context.Context {
+	cfg := newConfig(opts...)
+	return registerAggregator(ctx, typedContextKey[T](cfg.keys...), newAggregator[T](ctx, cfg))
+}
+
+// RegisterAggregator registers a user-provided aggregator into context under the
+// same key scheme as Register, so it works with Collect, Aggregate and the
+// filter/transform helpers. In order to use many aggregators in a project,
+// please use different keys.
+func RegisterAggregator[T any](ctx context.Context, agg ContextAggregator[T], keys ...string) context.Context {
+	return registerAggregator(ctx, typedContextKey[T](keys...), agg)
+}
+
+// New creates an aggregator built from the given options without registering
+// it into a context. WithKey options are ignored.
+func New[T any](opts ...Option) ContextAggregator[T] {
+	return newAggregator[T](context.Background(), newConfig(opts...))
+}
+
+// NewBase creates a sequential aggregator without any asynchronous lock
+func NewBase[T any]() ContextAggregator[T] {
+	return New[T]()
+}
+
+// NewConcurrent creates an aggregator safe for collecting from multiple goroutines
+func NewConcurrent[T any]() ConcurrentContextAggregator[T] {
+	return New[T](WithConcurrency()).(ConcurrentContextAggregator[T])
+}
+
+// NewStreaming creates a sequential aggregator calling callback for every collected item
+func NewStreaming[T any](callback CollectCallback[T]) ContextAggregator[T] {
+	return New[T](WithCallback(callback))
+}
+
+// NewConcurrentStreaming creates a thread-safe aggregator calling callback for
+// every collected item
+func NewConcurrentStreaming[T any](callback CollectCallback[T]) ConcurrentContextAggregator[T] {
+	return New[T](WithConcurrency(), WithCallback(callback)).(ConcurrentContextAggregator[T])
+}
+
+// registerAggregator stores agg into context under ctxKey
+func registerAggregator[T any](ctx context.Context, ctxKey any, agg ContextAggregator[T]) context.Context {
+	reg := registryFrom(ctx).extend()
+	addAggregator(reg, ctxKey, agg)
+
+	return context.WithValue(ctx, registryKey{}, reg.compact())
+}
+
+// addAggregator stores agg into the top layer of reg under ctxKey, along with
+// its aliases
+func addAggregator[T any](reg *registry, ctxKey any, agg ContextAggregator[T]) {
+	reg.aggregators[ctxKey] = agg
+
+	if _, ok := ctxKey.(defaultContextKey[T]); ok {
+		reg.addKeyless(reflect.TypeFor[T](), agg)
+
+		// Untyped helpers such as WaitFunc cannot build the per-type default key,
+		// so the latest keyless aggregator stays reachable via the legacy key
+		reg.aggregators[contextAggregatorContextKey] = agg
+
+		if elem := reflect.TypeFor[T](); elem.Kind() == reflect.Interface {
+			reg.addInterface(elem, agg)
+		}
+	}
+}
+
+// newAggregator picks the aggregator implementation matching the config.
+// Asynchronous callbacks run until ctx is done.
+func newAggregator[T any](ctx context.Context, cfg *config) ContextAggregator[T] {
+	callbacks := newCallbacks[T](cfg)
+	orderKey := typedOption[func(T) uint64](cfg.orderKey, "order key")
+
+	switch {
+	case cfg.concurrent && callbacks.any():
+		agg := &concurrentStreamingAggregator[T]{
+			m:         &sync.Mutex{},
+			wg:        &sync.WaitGroup{},
+			store:     newStore[T](cfg),
+			callbacks: callbacks,
+		}
+		if cfg.async {
+			agg.async = newDispatcher(ctx, cfg, callbacks, orderKey)
+		}
+		return agg
+	case cfg.sharded && cfg.maxItems <= 0:
+		return newShardedAggregator[T](cfg)
+	case cfg.concurrent:
+		return &concurrentAggregator[T]{
+			m:     &sync.Mutex{},
+			wg:    &sync.WaitGroup{},
+			store: newStore[T](cfg),
+		}
+	case callbacks.any():
+		return &streamingAggregator[T]{
+			store:     newStore[T](cfg),
+			callbacks: callbacks,
+		}
+	default:
+		return &baseAggregator[T]{
+			store: newStore[T](cfg),
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package aggregator
+
+import (
+	"context"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// registryKey is the context key of the registry holding every aggregator
+// registered in a context
+type registryKey struct{}
+
+// registry maps context keys to aggregators so a lookup is a single ctx.Value
+// call followed by one map access per layer, at most log2(n)+1 for n
+// registered aggregators, instead of one ctx.Value call per aggregator.
+//
+// A registry is never modified once it is stored in a context, so contexts
+// derived before a registration keep their own view, following context
+// conventions. Registering adds a layer holding the new aggregator on top of
+// the nearest registry instead of copying it. A layer is merged into the one
+// below as soon as it is as large, like a binary counter, so a registry of n
+// aggregators has at most log2(n)+1 layers and each registration copies
+// O(log n) entries on average.
+type registry struct {
+	aggregators map[any]any
+	// parent is the layer below, shadowed by this one
+	parent *registry
+
+	// interfaces lists keyless aggregators whose element type is an interface,
+	// most recently registered first
+	interfaces []typedAggregator
+	// waitable lists keyless aggregators implementing IConcurrentAggregator, one
+	// per type, so WaitFunc without keys can tell which one to wait on
+	waitable []typedAggregator
+}
+
+// typedAggregator is an aggregator along with its element type
+type typedAggregator struct {
+	elem reflect.Type
+	agg  any
+}
+
+// registryFrom returns the registry of ctx, or nil if nothing was registered
+func registryFrom(ctx context.Context) *registry {
+	reg, _ := ctx.Value(registryKey{}).(*registry)
+	return reg
+}
+
+// extend returns an empty layer on top of r that can be modified. It is safe
+// to call on nil.
+func (r *registry) extend() *registry {
+	layer := &registry{aggregators: make(map[any]any, 1), parent: r}
+	if r != nil {
+		layer.interfaces = r.interfaces
+		layer.waitable = r.waitable
+	}
+
+	return layer
+}
+
+// compact merges r into the layers below as long as it is at least as large
+// as the layer below, and returns the top layer. r must not be shared yet; the
+// layers below are copied, never modified.
+func (r *registry) compact() *registry {
+	for r.parent != nil && r.len() >= r.parent.len() {
+		parent := r.parent
+		merged := &registry{
+			aggregators: maps.Clone(parent.aggregators),
+			parent:      parent.parent,
+			interfaces:  r.interfaces,
+			waitable:    r.waitable,
+		}
+		maps.Copy(merged.aggregators, r.aggregators)
+
+		r = merged
+	}
+
+	return r
+}
+
+// len returns the number of entries of the layer r, ignoring the layers below
+func (r *registry) len() int {
+	return len(r.aggregators)
+}
+
+// lookup returns the aggregator stored under ctxKey, walking the layers from the
+// top. It is safe to call on nil.
+func (r *registry) lookup(ctxKey any) any {
+	for ; r != nil; r = r.parent {
+		if agg, ok := r.aggregators[ctxKey]; ok {
+			return agg
+		}
+	}
+
+	return nil
+}
+
+// all returns every key and the aggregator stored under it, skipping the
+// entries shadowed by an upper layer. It is safe to call on nil.
+func (r *registry) all() iter.Seq2[any, any] {
+	return func(yield func(any, any) bool) {
+		seen := make(map[any]struct{})
+		for layer := r; layer != nil; layer = layer.parent {
+			for ctxKey, agg := range layer.aggregators {
+				if _, ok := seen[ctxKey]; ok {
+					continue
+				}
+				seen[ctxKey] = struct{}{}
+
+				if !yield(ctxKey, agg) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// addInterface records a keyless aggregator of interface type elem, shadowing
+// any previous one of the same type
+func (r *registry) addInterface(elem reflect.Type, agg any) {
+	r.interfaces = shadow(r.interfaces, elem, agg)
+}
+
+// addKeyless records a keyless aggregator of type elem, shadowing any previous
+// one of the same type, including in the waitable aggregators if it cannot be
+// waited on
+func (r *registry) addKeyless(elem reflect.Type, agg any) {
+	if _, ok := agg.(IConcurrentAggregator); ok {
+		r.waitable = shadow(r.waitable, elem, agg)
+	} else if slices.ContainsFunc(r.waitable, func(entry typedAggregator) bool { return entry.elem == elem }) {
+		r.waitable = slices.DeleteFunc(slices.Clone(r.waitable), func(entry typedAggregator) bool { return entry.elem == elem })
+	}
+}
+
+// shadow returns a copy of entries starting with agg of type elem, without the
+// previous entry of that type
+func shadow(entries []typedAggregator, elem reflect.Type, agg any) []typedAggregator {
+	shadowed := make([]typedAggregator, 0, len(entries)+1)
+	shadowed = append(shadowed, typedAggregator{elem: elem, agg: agg})
+	for _, entry := range entries {
+		if entry.elem != elem {
+			shadowed = append(shadowed, entry)
+		}
+	}
+
+	return shadowed
+}
+
+// lookupValue returns the aggregator stored in ctx under ctxKey
+func lookupValue(ctx context.Context, ctxKey any) any {
+	return registryFrom(ctx).lookup(ctxKey)
+}
@@ -0,0 +1,400 @@
+package aggregator
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+var _ SampledAggregator[any] = new(reservoirAggregator[any])
+var _ SealableAggregator[any] = new(reservoirAggregator[any])
+var _ BoundedAggregator[any] = new(reservoirAggregator[any])
+var _ inspector = new(reservoirAggregator[any])
+var _ ConcurrentContextAggregator[any] = new(concurrentReservoirAggregator[any])
+var _ SampledAggregator[any] = new(concurrentReservoirAggregator[any])
+var _ SealableAggregator[any] = new(concurrentReservoirAggregator[any])
+var _ BoundedAggregator[any] = new(concurrentReservoirAggregator[any])
+var _ inspector = new(concurrentReservoirAggregator[any])
+
+// maxSkip bounds the number of items Algorithm L skips at once, far beyond
+// anything a context can collect
+const maxSkip = 1 << 53
+
+// SampledAggregator is implemented by aggregators keeping a sample of the
+// collected items rather than all of them
+type SampledAggregator[T any] interface {
+	ContextAggregator[T]
+	// AggregateSample returns the sample along with the number of items seen
+	AggregateSample() (sample []T, seen int)
+}
+
+// WithSeed seeds the random source of sampling aggregators, making their
+// samples deterministic, e.g. in tests
+func WithSeed(seed uint64) Option {
+	return func(c *config) {
+		c.seed = seed
+		c.seeded = true
+	}
+}
+
+// RegisterReservoirAggregator registers an aggregator keeping a uniform random
+// sample of at most size of the collected items, using reservoir sampling. It
+// uses constant memory however many items are collected, and tracks how many
+// were seen.
+//
+// The concurrent variant, with WithConcurrency, samples into one reservoir per
+// P, picked round-robin, and merges them on Aggregate, so collectors rarely wait
+// on the same lock. They still share the atomic counter picking the reservoir,
+// which keeps the samples deterministic under WithSeed when collected from a
+// single goroutine.
+//
+// WithConcurrency, WithKey, WithSeed and WithLateCollectHook are honored, other
+// options are ignored. RegisterReservoirAggregator panics if size is not positive.
+func RegisterReservoirAggregator[T any](ctx context.Context, size int, opts ...Option) context.Context {
+	cfg := newConfig(opts...)
+	return registerAggregator(ctx, typedContextKey[T](cfg.keys...), newReservoirAggregator[T](size, cfg))
+}
+
+// NewReservoir creates a reservoir aggregator without registering it into a context
+func NewReservoir[T any](size int, opts ...Option) ContextAggregator[T] {
+	return newReservoirAggregator[T](size, newConfig(opts...))
+}
+
+// AggregateSample aggregates the sample of the aggregator of type T along with
+// the number of items it has seen. Aggregators that do not implement
+// SampledAggregator keep every item, so their sample is everything.
+func AggregateSample[T any](ctx context.Context, keys ...string) ([]T, int, error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if sampled, ok := agg.(SampledAggregator[T]); ok {
+		sample, seen := sampled.AggregateSample()
+		return sample, seen, nil
+	}
+
+	items := agg.Aggregate()
+	return items, len(items), nil
+}
+
+func newReservoirAggregator[T any](size int, cfg *config) ContextAggregator[T] {
+	if size <= 0 {
+		panic("aggregator: reservoir size must be positive")
+	}
+
+	if !cfg.concurrent {
+		agg := &reservoirAggregator[T]{}
+		agg.init(newReservoir[T](size, newRand(cfg, 0)), KindReservoir, cfg)
+		return agg
+	}
+
+	shards := make([]reservoirShard[T], runtime.GOMAXPROCS(0))
+	for i := range shards {
+		shards[i].lifecycle = newLifecycle[T](cfg)
+		shards[i].reservoir = *newReservoir[T](size, newRand(cfg, uint64(i)+1))
+	}
+
+	return &concurrentReservoirAggregator[T]{
+		m:      &sync.Mutex{},
+		shards: shards,
+		size:   size,
+		rng:    newRand(cfg, 0),
+	}
+}
+
+// newRand returns the random source of stream, seeded by WithSeed if given
+func newRand(cfg *config, stream uint64) *rand.Rand {
+	if cfg.seeded {
+		return rand.New(rand.NewPCG(cfg.seed, stream))
+	}
+
+	return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+}
+
+// reservoir samples items with Algorithm L: once full, it computes how many
+// items to skip before the next replacement instead of drawing a random number
+// per item
+type reservoir[T any] struct {
+	items []T
+	size  int
+	seen  int
+	// next is the index of the next item to keep, w the current weight
+	next int
+	w    float64
+	rng  *rand.Rand
+}
+
+func newReservoir[T any](size int, rng *rand.Rand) *reservoir[T] {
+	return &reservoir[T]{
+		items: make([]T, 0, size),
+		size:  size,
+		rng:   rng,
+	}
+}
+
+func (r *reservoir[T]) add(data T) {
+	i := r.seen
+	r.seen++
+
+	if i < r.size {
+		r.items = append(r.items, data)
+		if r.seen == r.size {
+			r.w = r.weight()
+			r.next = i
+			r.skip()
+		}
+		return
+	}
+
+	if i == r.next {
+		r.items[r.rng.IntN(r.size)] = data
+		r.w *= r.weight()
+		r.skip()
+	}
+}
+
+func (r *reservoir[T]) weight() float64 {
+	return math.Exp(math.Log(r.unit()) / float64(r.size))
+}
+
+// skip moves next past the items that will not be kept
+func (r *reservoir[T]) skip() {
+	skip := math.Floor(math.Log(r.unit()) / math.Log1p(-r.w))
+	if !(skip >= 0 && skip <= maxSkip) {
+		skip = maxSkip
+	}
+
+	r.next += int(skip) + 1
+}
+
+// unit returns a random number in (0, 1)
+func (r *reservoir[T]) unit() float64 {
+	for {
+		if u := r.rng.Float64(); u > 0 {
+			return u
+		}
+	}
+}
+
+func (r *reservoir[T]) snapshot() []T {
+	return slices.Clone(r.items)
+}
+
+func (r *reservoir[T]) drain() []T {
+	items := r.snapshot()
+	r.reset()
+
+	return items
+}
+
+func (r *reservoir[T]) reset() {
+	clear(r.items)
+	r.items = r.items[:0]
+	r.seen = 0
+	r.next = 0
+	r.w = 0
+}
+
+func (r *reservoir[T]) dropped() int {
+	return r.seen - len(r.items)
+}
+
+func (r *reservoir[T]) info() AggregatorInfo {
+	return AggregatorInfo{
+		Len:      len(r.items),
+		Cap:      r.size,
+		MaxItems: r.size,
+		Dropped:  r.dropped(),
+	}
+}
+
+// reservoirAggregator is a sequential reservoir aggregator
+type reservoirAggregator[T any] struct {
+	sequentialBounded[T, *reservoir[T]]
+}
+
+func (a *reservoirAggregator[T]) AggregateSample() ([]T, int) {
+	return a.items.snapshot(), a.items.seen
+}
+
+type reservoirShard[T any] struct {
+	m sync.Mutex
+	lifecycle[T]
+	reservoir[T]
+}
+
+// concurrentReservoirAggregator is a thread-safe reservoir aggregator. Items
+// are spread round-robin over per-P reservoirs, each guarded by its own mutex
+// and sealed along with the others. Only the turn counter is shared.
+type concurrentReservoirAggregator[T any] struct {
+	waitGroup
+	// m guards rng, used to merge the shards
+	m      *sync.Mutex
+	shards []reservoirShard[T]
+	turn   atomic.Uint32
+	size   int
+	rng    *rand.Rand
+}
+
+func (a *concurrentReservoirAggregator[T]) Collect(data T) {
+	_ = a.TryCollect(data)
+}
+
+func (a *concurrentReservoirAggregator[T]) TryCollect(data T) error {
+	shard := a.next()
+
+	shard.m.Lock()
+	err := shard.reject(1)
+	if err == nil {
+		shard.add(data)
+	}
+	shard.m.Unlock()
+
+	return shard.settle(data, err)
+}
+
+// collectMany samples a whole batch into a single reservoir
+func (a *concurrentReservoirAggregator[T]) collectMany(_ context.Context, items []T) error {
+	shard := a.next()
+
+	shard.m.Lock()
+	err := shard.reject(len(items))
+	if err == nil {
+		for _, item := range items {
+			shard.add(item)
+		}
+	}
+	shard.m.Unlock()
+
+	return shard.settleMany(items, err)
+}
+
+// next returns the reservoir whose turn it is
+func (a *concurrentReservoirAggregator[T]) next() *reservoirShard[T] {
+	// The modulo is computed on the unsigned counter, which wraps around
+	return &a.shards[(a.turn.Add(1)-1)%uint32(len(a.shards))]
+}
+
+func (a *concurrentReservoirAggregator[T]) Aggregate() []T {
+	sample, _ := a.AggregateSample()
+	return sample
+}
+
+func (a *concurrentReservoirAggregator[T]) AggregateSample() ([]T, int) {
+	a.wg.Wait()
+
+	return a.merge(func(*reservoirShard[T]) {})
+}
+
+func (a *concurrentReservoirAggregator[T]) AggregateWithDropped() ([]T, int) {
+	sample, seen := a.AggregateSample()
+	return sample, seen - len(sample)
+}
+
+func (a *concurrentReservoirAggregator[T]) Drain() []T {
+	sample, _ := a.merge(func(s *reservoirShard[T]) { s.reset() })
+	return sample
+}
+
+func (a *concurrentReservoirAggregator[T]) Seal() {
+	a.each(func(s *reservoirShard[T]) { s.sealed = true })
+}
+
+func (a *concurrentReservoirAggregator[T]) AggregateAndSeal() []T {
+	a.wg.Wait()
+
+	sample, _ := a.merge(func(s *reservoirShard[T]) { s.sealed = true })
+	return sample
+}
+
+func (a *concurrentReservoirAggregator[T]) LateCollects() int {
+	late := 0
+	a.each(func(s *reservoirShard[T]) { late += s.late })
+
+	return late
+}
+
+// each calls fn with every shard, one at a time under its lock
+func (a *concurrentReservoirAggregator[T]) each(fn func(*reservoirShard[T])) {
+	for i := range a.shards {
+		shard := &a.shards[i]
+		shard.m.Lock()
+		fn(shard)
+		shard.m.Unlock()
+	}
+}
+
+// merge copies the sample of every shard before applying then to it, and
+// returns a uniform sample of everything the shards have seen
+func (a *concurrentReservoirAggregator[T]) merge(then func(*reservoirShard[T])) ([]T, int) {
+	samples := make([][]T, 0, len(a.shards))
+	seen := make([]int, 0, len(a.shards))
+	a.each(func(s *reservoirShard[T]) {
+		samples = append(samples, s.snapshot())
+		seen = append(seen, s.seen)
+		then(s)
+	})
+
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	return mergeSamples(a.rng, a.size, samples, seen)
+}
+
+func (a *concurrentReservoirAggregator[T]) inspect() AggregatorInfo {
+	info := AggregatorInfo{
+		ElemType: elemTypeName[T](),
+		Kind:     KindConcurrentReservoir,
+		Cap:      a.size,
+		Waiters:  int(a.waiters.Load()),
+		MaxItems: a.size,
+	}
+
+	seen := 0
+	a.each(func(s *reservoirShard[T]) {
+		seen += s.seen
+		info.Sealed = s.sealed
+		info.LateCollects += s.late
+	})
+	info.Len = min(seen, a.size)
+	info.Dropped = seen - info.Len
+
+	return info
+}
+
+// mergeSamples combines uniform samples of disjoint streams, where seen[i] items
+// went through stream i, into a uniform sample of at most size items of their
+// union. Each draw picks a stream with probability proportional to its items
+// not drawn yet, then a random item from its sample. samples are consumed.
+func mergeSamples[T any](rng *rand.Rand, size int, samples [][]T, seen []int) ([]T, int) {
+	total := 0
+	for _, n := range seen {
+		total += n
+	}
+
+	remaining := total
+	merged := make([]T, 0, min(size, total))
+	for len(merged) < cap(merged) {
+		pick := rng.IntN(remaining)
+		i := 0
+		for pick >= seen[i] {
+			pick -= seen[i]
+			i++
+		}
+		seen[i]--
+		remaining--
+
+		sample := samples[i]
+		j := rng.IntN(len(sample))
+		merged = append(merged, sample[j])
+		sample[j] = sample[len(sample)-1]
+		samples[i] = sample[:len(sample)-1]
+	}
+
+	return merged, total
+}
@@ -0,0 +1,110 @@
+package aggregator
+
+import (
+	"context"
+)
+
+var _ SealableAggregator[any] = new(ringAggregator[any])
+var _ BoundedAggregator[any] = new(ringAggregator[any])
+var _ inspector = new(ringAggregator[any])
+var _ ConcurrentContextAggregator[any] = new(concurrentRingAggregator[any])
+var _ SealableAggregator[any] = new(concurrentRingAggregator[any])
+var _ BoundedAggregator[any] = new(concurrentRingAggregator[any])
+var _ inspector = new(concurrentRingAggregator[any])
+
+// RegisterRingAggregator registers an aggregator keeping only the last size
+// collected items in a fixed-size circular buffer, e.g. the last debug events
+// before an error. The buffer is allocated upfront, so collecting never
+// allocates, and Aggregate returns items oldest first.
+//
+// WithConcurrency, WithKey and WithLateCollectHook are honored, other options
+// are ignored. RegisterRingAggregator panics if size is not positive.
+func RegisterRingAggregator[T any](ctx context.Context, size int, opts ...Option) context.Context {
+	cfg := newConfig(opts...)
+	return registerAggregator(ctx, typedContextKey[T](cfg.keys...), newRingAggregator[T](size, cfg))
+}
+
+// NewRing creates a ring aggregator without registering it into a context
+func NewRing[T any](size int, opts ...Option) ContextAggregator[T] {
+	return newRingAggregator[T](size, newConfig(opts...))
+}
+
+func newRingAggregator[T any](size int, cfg *config) ContextAggregator[T] {
+	if size <= 0 {
+		panic("aggregator: ring size must be positive")
+	}
+
+	r := &ring[T]{buf: make([]T, size)}
+	if cfg.concurrent {
+		agg := &concurrentRingAggregator[T]{}
+		agg.init(r, KindConcurrentRing, cfg)
+		return agg
+	}
+
+	agg := &ringAggregator[T]{}
+	agg.init(r, KindRing, cfg)
+	return agg
+}
+
+// ring is the circular buffer kept by the ring aggregators
+type ring[T any] struct {
+	buf   []T
+	next  int
+	count int
+	// overwritten counts items dropped to make room for newer ones
+	overwritten int
+}
+
+func (r *ring[T]) add(data T) {
+	r.buf[r.next] = data
+	r.next = (r.next + 1) % len(r.buf)
+	if r.count < len(r.buf) {
+		r.count++
+	} else {
+		r.overwritten++
+	}
+}
+
+// snapshot returns the buffered items oldest first
+func (r *ring[T]) snapshot() []T {
+	items := make([]T, r.count)
+	start := (r.next - r.count + len(r.buf)) % len(r.buf)
+	n := copy(items, r.buf[start:])
+	if n < r.count {
+		copy(items[n:], r.buf[:r.count-n])
+	}
+
+	return items
+}
+
+func (r *ring[T]) drain() []T {
+	items := r.snapshot()
+	clear(r.buf)
+	r.next = 0
+	r.count = 0
+
+	return items
+}
+
+func (r *ring[T]) dropped() int {
+	return r.overwritten
+}
+
+func (r *ring[T]) info() AggregatorInfo {
+	return AggregatorInfo{
+		Len:      r.count,
+		Cap:      len(r.buf),
+		MaxItems: len(r.buf),
+		Dropped:  r.overwritten,
+	}
+}
+
+// ringAggregator is a sequential ring aggregator
+type ringAggregator[T any] struct {
+	sequentialBounded[T, *ring[T]]
+}
+
+// concurrentRingAggregator is a thread-safe ring aggregator
+type concurrentRingAggregator[T any] struct {
+	synchronizedBounded[T, *ring[T]]
+}
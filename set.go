@@ -0,0 +1,128 @@
+package aggregator
+
+import (
+	"context"
+	"slices"
+)
+
+var _ SealableAggregator[any] = new(setAggregator[any, int])
+var _ BoundedAggregator[any] = new(setAggregator[any, int])
+var _ inspector = new(setAggregator[any, int])
+var _ ConcurrentContextAggregator[any] = new(concurrentSetAggregator[any, int])
+var _ SealableAggregator[any] = new(concurrentSetAggregator[any, int])
+var _ BoundedAggregator[any] = new(concurrentSetAggregator[any, int])
+var _ inspector = new(concurrentSetAggregator[any, int])
+
+// DuplicatePolicy decides which item a set aggregator keeps when several
+// collected items share a key
+type DuplicatePolicy int
+
+const (
+	// KeepFirst keeps the first item collected for a key and ignores the others
+	KeepFirst DuplicatePolicy = iota
+	// KeepLast replaces the stored item with every later item of the same key.
+	// The item keeps the position of the first one.
+	KeepLast
+)
+
+// WithDuplicatePolicy sets which item set aggregators keep per key. The
+// default is KeepFirst.
+func WithDuplicatePolicy(policy DuplicatePolicy) Option {
+	return func(c *config) {
+		c.duplicates = policy
+	}
+}
+
+// RegisterSetAggregator registers an aggregator keeping one item per key
+// returned by keyFn, e.g. the tables touched by a request. Aggregate returns
+// the items in the order their key was first collected.
+//
+// WithConcurrency, WithKey, WithDuplicatePolicy and WithLateCollectHook are
+// honored, other options are ignored. The aggregator is registered for T, so
+// the Collect and Aggregate functions work as with any other aggregator.
+func RegisterSetAggregator[T any, K comparable](ctx context.Context, keyFn func(T) K, opts ...Option) context.Context {
+	cfg := newConfig(opts...)
+	return registerAggregator(ctx, typedContextKey[T](cfg.keys...), newSetAggregator(keyFn, cfg))
+}
+
+// NewSet creates a set aggregator without registering it into a context
+func NewSet[T any, K comparable](keyFn func(T) K, opts ...Option) ContextAggregator[T] {
+	return newSetAggregator(keyFn, newConfig(opts...))
+}
+
+func newSetAggregator[T any, K comparable](keyFn func(T) K, cfg *config) ContextAggregator[T] {
+	s := &set[T, K]{
+		keyFn:  keyFn,
+		index:  make(map[K]int, cfg.capacity),
+		items:  make([]T, 0, cfg.capacity),
+		policy: cfg.duplicates,
+	}
+	if cfg.concurrent {
+		agg := &concurrentSetAggregator[T, K]{}
+		agg.init(s, KindConcurrentSet, cfg)
+		return agg
+	}
+
+	agg := &setAggregator[T, K]{}
+	agg.init(s, KindSet, cfg)
+	return agg
+}
+
+// set is the collection kept by the set aggregators
+type set[T any, K comparable] struct {
+	keyFn  func(T) K
+	index  map[K]int
+	items  []T
+	policy DuplicatePolicy
+	// duplicates counts the items discarded or replaced because of their key
+	duplicates int
+}
+
+func (s *set[T, K]) add(data T) {
+	key := s.keyFn(data)
+	if i, ok := s.index[key]; ok {
+		s.duplicates++
+		if s.policy == KeepLast {
+			s.items[i] = data
+		}
+		return
+	}
+
+	s.index[key] = len(s.items)
+	s.items = append(s.items, data)
+}
+
+func (s *set[T, K]) snapshot() []T {
+	return slices.Clone(s.items)
+}
+
+func (s *set[T, K]) drain() []T {
+	items := s.items
+	s.items = make([]T, 0, len(items))
+	clear(s.index)
+
+	return items
+}
+
+// dropped returns the number of duplicates
+func (s *set[T, K]) dropped() int {
+	return s.duplicates
+}
+
+func (s *set[T, K]) info() AggregatorInfo {
+	return AggregatorInfo{
+		Len:     len(s.items),
+		Cap:     cap(s.items),
+		Dropped: s.duplicates,
+	}
+}
+
+// setAggregator is a sequential set aggregator
+type setAggregator[T any, K comparable] struct {
+	sequentialBounded[T, *set[T, K]]
+}
+
+// concurrentSetAggregator is a thread-safe set aggregator
+type concurrentSetAggregator[T any, K comparable] struct {
+	synchronizedBounded[T, *set[T, K]]
+}
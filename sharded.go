@@ -0,0 +1,265 @@
+package aggregator
+
+import (
+	"context"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+var _ ConcurrentContextAggregator[any] = new(shardedAggregator[any])
+var _ SealableAggregator[any] = new(shardedAggregator[any])
+var _ BoundedAggregator[any] = new(shardedAggregator[any])
+var _ inspector = new(shardedAggregator[any])
+
+// WithShards makes a concurrent aggregator spread its items over n independently
+// locked shards, merged on Aggregate, so many goroutines collecting at once
+// rarely contend on a lock. n of zero or less uses GOMAXPROCS shards. It implies
+// WithConcurrency.
+//
+// Collectors stay on the same shard until it is contended, so items collected
+// by a single goroutine come back in collect order as with the single-lock
+// aggregator. Items collected concurrently are ordered by blocks of 64 items
+// per shard rather than one by one.
+//
+// WithCallback and WithMaxItems silently disable sharding: the aggregator then
+// falls back to the single-lock aggregator, as Inspect reports.
+func WithShards(n int) Option {
+	return func(c *config) {
+		c.concurrent = true
+		c.sharded = true
+		c.shards = n
+	}
+}
+
+// shardBlock is the number of sequence numbers a shard reserves at once
+const shardBlock = 64
+
+// run is a block of items of a shard, starting at index at of its items and
+// ordered among all shards by seq
+type run struct {
+	at  int
+	seq uint64
+}
+
+type shard[T any] struct {
+	m      sync.Mutex
+	items  []T
+	runs   []run
+	sealed bool
+	late   int
+	// keeps shards on separate cache lines
+	_ [64]byte
+}
+
+// add appends items, reserving a block of sequence numbers from seq whenever
+// the current one is used up
+func (s *shard[T]) add(seq *atomic.Uint64, items ...T) {
+	for len(items) > 0 {
+		room := 0
+		if len(s.runs) > 0 {
+			room = shardBlock - (len(s.items) - s.runs[len(s.runs)-1].at)
+		}
+		if room == 0 {
+			s.runs = append(s.runs, run{at: len(s.items), seq: seq.Add(shardBlock) - shardBlock})
+			room = shardBlock
+		}
+
+		n := min(room, len(items))
+		s.items = append(s.items, items[:n]...)
+		items = items[n:]
+	}
+}
+
+// shardedAggregator is a thread-safe aggregator collecting into the shard of
+// the latest uncontended collect. Each shard reserves blocks of sequence
+// numbers, so the shared counter is touched once per block, and merging the
+// blocks of all shards by sequence number keeps a lone collector's order.
+type shardedAggregator[T any] struct {
+	waitGroup
+	seq    atomic.Uint64
+	shards []shard[T]
+	// home is the shard collectors try first
+	home   atomic.Uint32
+	onLate LateCollectHook[T]
+}
+
+func newShardedAggregator[T any](cfg *config) *shardedAggregator[T] {
+	n := cfg.shards
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+
+	shards := make([]shard[T], n)
+	for i := range shards {
+		shards[i].items = make([]T, 0, cfg.capacity/n)
+	}
+
+	return &shardedAggregator[T]{
+		shards: shards,
+		onLate: typedOption[LateCollectHook[T]](cfg.lateHook, "late collect hook"),
+	}
+}
+
+// lock locks the home shard, or a random one once the home shard is contended,
+// which becomes the new home
+func (a *shardedAggregator[T]) lock() *shard[T] {
+	s := &a.shards[a.home.Load()]
+	if s.m.TryLock() {
+		return s
+	}
+
+	i := rand.Uint32N(uint32(len(a.shards)))
+	a.home.Store(i)
+	s = &a.shards[i]
+	s.m.Lock()
+
+	return s
+}
+
+func (a *shardedAggregator[T]) Collect(data T) {
+	_ = a.TryCollect(data)
+}
+
+func (a *shardedAggregator[T]) TryCollect(data T) error {
+	s := a.lock()
+	if s.sealed {
+		s.late++
+		s.m.Unlock()
+
+		if a.onLate != nil {
+			invokeCallback(CollectCallback[T](a.onLate), data)
+		}
+		return ErrAggregatorClosed
+	}
+	s.add(&a.seq, data)
+	s.m.Unlock()
+
+	return nil
+}
+
+func (a *shardedAggregator[T]) collectMany(_ context.Context, items []T) error {
+	s := a.lock()
+	if s.sealed {
+		s.late += len(items)
+		s.m.Unlock()
+
+		if a.onLate != nil {
+			for _, item := range items {
+				invokeCallback(CollectCallback[T](a.onLate), item)
+			}
+		}
+		return ErrAggregatorClosed
+	}
+	s.add(&a.seq, items...)
+	s.m.Unlock()
+
+	return nil
+}
+
+func (a *shardedAggregator[T]) Aggregate() []T {
+	// Always call Wait before lock mutex for not cause deadlock
+	a.wg.Wait()
+
+	return a.merge(func(*shard[T]) {})
+}
+
+func (a *shardedAggregator[T]) AggregateWithDropped() ([]T, int) {
+	return a.Aggregate(), 0
+}
+
+func (a *shardedAggregator[T]) Drain() []T {
+	return a.merge(func(s *shard[T]) {
+		s.items = make([]T, 0, cap(s.items))
+		s.runs = nil
+	})
+}
+
+func (a *shardedAggregator[T]) Seal() {
+	a.each(func(s *shard[T]) {
+		s.sealed = true
+	})
+}
+
+func (a *shardedAggregator[T]) AggregateAndSeal() []T {
+	a.wg.Wait()
+
+	return a.merge(func(s *shard[T]) {
+		s.sealed = true
+	})
+}
+
+func (a *shardedAggregator[T]) LateCollects() int {
+	late := 0
+	a.each(func(s *shard[T]) {
+		late += s.late
+	})
+
+	return late
+}
+
+// each calls fn with every shard, one at a time under its lock
+func (a *shardedAggregator[T]) each(fn func(*shard[T])) {
+	for i := range a.shards {
+		s := &a.shards[i]
+		s.m.Lock()
+		fn(s)
+		s.m.Unlock()
+	}
+}
+
+// merge returns the items of every shard ordered by run before applying then
+// to it. It holds every shard lock, in order, which is safe since collectors
+// hold a single one.
+func (a *shardedAggregator[T]) merge(then func(*shard[T])) []T {
+	total := 0
+	for i := range a.shards {
+		a.shards[i].m.Lock()
+		total += len(a.shards[i].items)
+	}
+
+	// next[i] is the index of the next run of shard i to merge
+	next := make([]int, len(a.shards))
+	items := make([]T, 0, total)
+	for len(items) < total {
+		first := -1
+		for i := range a.shards {
+			if next[i] < len(a.shards[i].runs) &&
+				(first < 0 || a.shards[i].runs[next[i]].seq < a.shards[first].runs[next[first]].seq) {
+				first = i
+			}
+		}
+
+		s := &a.shards[first]
+		end := len(s.items)
+		if next[first]+1 < len(s.runs) {
+			end = s.runs[next[first]+1].at
+		}
+		items = append(items, s.items[s.runs[next[first]].at:end]...)
+		next[first]++
+	}
+
+	for i := range a.shards {
+		then(&a.shards[i])
+		a.shards[i].m.Unlock()
+	}
+
+	return items
+}
+
+func (a *shardedAggregator[T]) inspect() AggregatorInfo {
+	info := AggregatorInfo{
+		ElemType: elemTypeName[T](),
+		Kind:     KindSharded,
+		Waiters:  int(a.waiters.Load()),
+	}
+	a.each(func(s *shard[T]) {
+		info.Len += len(s.items)
+		info.Cap += cap(s.items)
+		info.Sealed = s.sealed
+		info.LateCollects += s.late
+	})
+
+	return info
+}
@@ -0,0 +1,171 @@
+package aggregator
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	// defaultRelativeAccuracy is the relative accuracy of quantiles when
+	// WithRelativeAccuracy is not given
+	defaultRelativeAccuracy = 0.01
+	// sketchRange is the ratio between the largest and the smallest value a
+	// sketch store tracks accurately. Values further below the largest one are
+	// collapsed into its lowest bin, bounding memory.
+	sketchRange = 1e12
+)
+
+// sketch is a DDSketch: values are counted in logarithmic buckets so that any
+// quantile is returned with a bounded relative error, using memory that grows
+// with the log of the value range rather than the number of values. Sketches
+// with the same accuracy merge exactly by adding their buckets.
+type sketch struct {
+	gamma float64
+	// multiplier is 1/log(gamma), turning a value into its bucket index
+	multiplier float64
+	// maxBins is the number of bins covering sketchRange
+	maxBins  int
+	positive sketchStore
+	negative sketchStore
+	zeros    int
+}
+
+func newSketch(relativeAccuracy float64) *sketch {
+	if !(relativeAccuracy > 0 && relativeAccuracy < 1) {
+		relativeAccuracy = defaultRelativeAccuracy
+	}
+
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	multiplier := 1 / math.Log(gamma)
+	return &sketch{
+		gamma:      gamma,
+		multiplier: multiplier,
+		maxBins:    int(math.Ceil(math.Log(sketchRange) * multiplier)),
+	}
+}
+
+func (s *sketch) add(value float64) {
+	switch {
+	case math.IsNaN(value) || math.IsInf(value, 0):
+		return
+	case value > 0:
+		s.positive.add(s.index(value), 1, s.maxBins)
+	case value < 0:
+		s.negative.add(s.index(-value), 1, s.maxBins)
+	default:
+		s.zeros++
+	}
+}
+
+func (s *sketch) count() int {
+	return s.positive.count + s.negative.count + s.zeros
+}
+
+// index returns the bucket of a positive value: gamma^(i-1) < value <= gamma^i
+func (s *sketch) index(value float64) int {
+	return int(math.Ceil(math.Log(value) * s.multiplier))
+}
+
+// value returns the representative of bucket i, whose relative distance to any
+// value of the bucket is at most the relative accuracy
+func (s *sketch) value(i int) float64 {
+	return math.Exp(float64(i)/s.multiplier) * 2 / (1 + s.gamma)
+}
+
+// quantile returns the value at quantile q in [0, 1], or NaN if empty
+func (s *sketch) quantile(q float64) float64 {
+	total := s.count()
+	if total == 0 || q < 0 || q > 1 {
+		return math.NaN()
+	}
+
+	rank := int(q * float64(total-1))
+
+	// Most negative first: the highest indices of the negative store
+	for i := len(s.negative.bins) - 1; i >= 0; i-- {
+		rank -= s.negative.bins[i]
+		if rank < 0 {
+			return -s.value(i + s.negative.offset)
+		}
+	}
+
+	rank -= s.zeros
+	if rank < 0 {
+		return 0
+	}
+
+	for i, n := range s.positive.bins {
+		rank -= n
+		if rank < 0 {
+			return s.value(i + s.positive.offset)
+		}
+	}
+
+	// Unreachable as rank < total
+	return math.NaN()
+}
+
+// merge adds the values of other into s
+func (s *sketch) merge(other *sketch) {
+	s.zeros += other.zeros
+	s.positive.merge(&other.positive, s, other)
+	s.negative.merge(&other.negative, s, other)
+}
+
+func (s *sketch) clone() *sketch {
+	c := *s
+	c.positive.bins = slices.Clone(s.positive.bins)
+	c.negative.bins = slices.Clone(s.negative.bins)
+
+	return &c
+}
+
+// sketchStore counts values per bucket index in a dense slice starting at offset
+type sketchStore struct {
+	bins   []int
+	offset int
+	count  int
+}
+
+func (st *sketchStore) add(index int, n int, maxBins int) {
+	switch {
+	case len(st.bins) == 0:
+		st.bins = append(st.bins, 0)
+		st.offset = index
+	case index < st.offset:
+		if high := st.offset + len(st.bins); high-index > maxBins {
+			index = high - maxBins
+		}
+		if grow := st.offset - index; grow > 0 {
+			st.bins = slices.Insert(st.bins, 0, make([]int, grow)...)
+			st.offset = index
+		}
+	case index >= st.offset+len(st.bins):
+		st.bins = append(st.bins, make([]int, index-st.offset-len(st.bins)+1)...)
+		if extra := len(st.bins) - maxBins; extra > 0 {
+			for _, c := range st.bins[:extra] {
+				st.bins[extra] += c
+			}
+			st.bins = slices.Delete(st.bins, 0, extra)
+			st.offset += extra
+		}
+	}
+
+	st.bins[index-st.offset] += n
+	st.count += n
+}
+
+// merge adds the bins of other, whose sketch is from, into st, whose sketch is to
+func (st *sketchStore) merge(other *sketchStore, to, from *sketch) {
+	for i, n := range other.bins {
+		if n == 0 {
+			continue
+		}
+
+		index := i + other.offset
+		if to.multiplier != from.multiplier {
+			index = to.index(from.value(index))
+		}
+		st.add(index, n, to.maxBins)
+	}
+}
@@ -0,0 +1,278 @@
+package aggregator
+
+import (
+	"context"
+	"math"
+)
+
+var _ SummaryAggregator[int] = new(statsAggregator[int])
+var _ SealableAggregator[int] = new(statsAggregator[int])
+var _ inspector = new(statsAggregator[int])
+var _ ConcurrentContextAggregator[int] = new(concurrentStatsAggregator[int])
+var _ SummaryAggregator[int] = new(concurrentStatsAggregator[int])
+var _ SealableAggregator[int] = new(concurrentStatsAggregator[int])
+var _ inspector = new(concurrentStatsAggregator[int])
+
+// Number is the set of numeric types a statistics aggregator accepts,
+// including named types such as time.Duration
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Summary describes the numbers collected by a statistics aggregator
+type Summary[T Number] struct {
+	Count int
+	// Sum is the total of the values, which may overflow small integer types
+	Sum T
+	Min T
+	Max T
+	// Mean is the arithmetic mean, or NaN if Count is zero
+	Mean float64
+	// Variance is the population variance, or NaN if Count is zero
+	Variance float64
+
+	sketch *sketch
+}
+
+// StdDev returns the population standard deviation
+func (s Summary[T]) StdDev() float64 {
+	return math.Sqrt(s.Variance)
+}
+
+// Quantile returns an approximation of the value at quantile q in [0, 1], e.g.
+// 0.99 for the p99, within the relative accuracy of the aggregator. It returns
+// zero if the summary is empty or q is out of range.
+func (s Summary[T]) Quantile(q float64) T {
+	if s.sketch == nil {
+		return 0
+	}
+
+	value := s.sketch.quantile(q)
+	if math.IsNaN(value) {
+		return 0
+	}
+
+	// The exact extremes are known, keep the approximation within them
+	return fromFloat[T](min(max(value, float64(s.Min)), float64(s.Max)))
+}
+
+// Merge combines two summaries as if every value had been collected into one
+// aggregator, e.g. to summarize many requests
+func (s Summary[T]) Merge(other Summary[T]) Summary[T] {
+	if other.Count == 0 {
+		return s
+	}
+	if s.Count == 0 {
+		return other
+	}
+
+	merged := newRunningStats[T](0)
+	if s.sketch != nil {
+		merged.sketch = s.sketch.clone()
+	}
+	merged.fromSummary(s)
+	merged.merge(other)
+
+	return merged.summary()
+}
+
+// WithRelativeAccuracy sets the relative accuracy of the quantiles computed by
+// statistics aggregators, 0.01 by default: a quantile of 100ms is reported
+// between 99ms and 101ms. Lower values use more memory.
+func WithRelativeAccuracy(accuracy float64) Option {
+	return func(c *config) {
+		c.accuracy = accuracy
+	}
+}
+
+// SummaryAggregator is implemented by aggregators summarizing numbers as they
+// are collected
+type SummaryAggregator[T Number] interface {
+	ContextAggregator[T]
+	AggregateSummary() Summary[T]
+}
+
+// RegisterStatsAggregator registers an aggregator computing count, sum, mean,
+// variance, min, max and approximate quantiles of the collected numbers, e.g.
+// latencies or payload sizes, without keeping them. Quantiles come from a
+// mergeable DDSketch. Aggregate returns nil since values are not kept, use
+// AggregateSummary instead.
+//
+// WithConcurrency, WithKey, WithRelativeAccuracy and WithLateCollectHook are
+// honored, other options are ignored.
+func RegisterStatsAggregator[T Number](ctx context.Context, opts ...Option) context.Context {
+	cfg := newConfig(opts...)
+	return registerAggregator(ctx, typedContextKey[T](cfg.keys...), newStatsAggregator[T](cfg))
+}
+
+// NewStats creates a statistics aggregator without registering it into a context
+func NewStats[T Number](opts ...Option) ContextAggregator[T] {
+	return newStatsAggregator[T](newConfig(opts...))
+}
+
+// AggregateSummary summarizes the numbers collected by the aggregator of type T.
+// Aggregators that do not implement SummaryAggregator have their items
+// summarized on the fly.
+func AggregateSummary[T Number](ctx context.Context, keys ...string) (Summary[T], error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return Summary[T]{}, err
+	}
+
+	if summarized, ok := agg.(SummaryAggregator[T]); ok {
+		return summarized.AggregateSummary(), nil
+	}
+
+	stats := newRunningStats[T](defaultRelativeAccuracy)
+	for _, item := range agg.Aggregate() {
+		stats.add(item)
+	}
+
+	return stats.summary(), nil
+}
+
+func newStatsAggregator[T Number](cfg *config) ContextAggregator[T] {
+	r := newRunningStats[T](cfg.accuracy)
+	if cfg.concurrent {
+		agg := &concurrentStatsAggregator[T]{}
+		agg.init(r, KindConcurrentStats, cfg)
+		return agg
+	}
+
+	agg := &statsAggregator[T]{}
+	agg.init(r, KindStats, cfg)
+	return agg
+}
+
+// runningStats is the collection kept by the statistics aggregators: the
+// moments and sketch of the values rather than the values. Mean and variance
+// are updated with Welford's algorithm.
+type runningStats[T Number] struct {
+	count int
+	sum   T
+	min   T
+	max   T
+	mean  float64
+	// m2 is the sum of squared differences from the mean
+	m2     float64
+	sketch *sketch
+}
+
+func newRunningStats[T Number](accuracy float64) *runningStats[T] {
+	return &runningStats[T]{sketch: newSketch(accuracy)}
+}
+
+func (r *runningStats[T]) add(data T) {
+	value := float64(data)
+	if r.count == 0 || data < r.min {
+		r.min = data
+	}
+	if r.count == 0 || data > r.max {
+		r.max = data
+	}
+
+	r.count++
+	r.sum += data
+	delta := value - r.mean
+	r.mean += delta / float64(r.count)
+	r.m2 += delta * (value - r.mean)
+	r.sketch.add(value)
+}
+
+// merge combines the moments of other with Chan's parallel algorithm
+func (r *runningStats[T]) merge(other Summary[T]) {
+	if other.Count == 0 {
+		return
+	}
+	if r.count == 0 || other.Min < r.min {
+		r.min = other.Min
+	}
+	if r.count == 0 || other.Max > r.max {
+		r.max = other.Max
+	}
+
+	count := r.count + other.Count
+	delta := other.Mean - r.mean
+	r.m2 += other.Variance*float64(other.Count) +
+		delta*delta*float64(r.count)*float64(other.Count)/float64(count)
+	r.mean += delta * float64(other.Count) / float64(count)
+	r.count = count
+	r.sum += other.Sum
+	if other.sketch != nil {
+		r.sketch.merge(other.sketch)
+	}
+}
+
+// fromSummary restores the moments of s, leaving the sketch alone
+func (r *runningStats[T]) fromSummary(s Summary[T]) {
+	r.count = s.Count
+	r.sum = s.Sum
+	r.min = s.Min
+	r.max = s.Max
+	r.mean = s.Mean
+	r.m2 = s.Variance * float64(s.Count)
+}
+
+func (r *runningStats[T]) summary() Summary[T] {
+	s := Summary[T]{
+		Count:    r.count,
+		Sum:      r.sum,
+		Min:      r.min,
+		Max:      r.max,
+		Mean:     math.NaN(),
+		Variance: math.NaN(),
+		sketch:   r.sketch.clone(),
+	}
+	if r.count > 0 {
+		s.Mean = r.mean
+		s.Variance = r.m2 / float64(r.count)
+	}
+
+	return s
+}
+
+// snapshot returns nil, since values are not kept
+func (r *runningStats[T]) snapshot() []T {
+	return nil
+}
+
+func (r *runningStats[T]) info() AggregatorInfo {
+	return AggregatorInfo{
+		Len: r.count,
+		Cap: -1,
+	}
+}
+
+// fromFloat converts value back to T, rounding to the nearest integer for
+// integer types
+func fromFloat[T Number](value float64) T {
+	half := 0.5
+	if T(half) != 0 {
+		return T(value)
+	}
+
+	return T(math.Round(value))
+}
+
+// statsAggregator is a sequential statistics aggregator
+type statsAggregator[T Number] struct {
+	sequential[T, *runningStats[T]]
+}
+
+func (a *statsAggregator[T]) AggregateSummary() Summary[T] {
+	return a.items.summary()
+}
+
+// concurrentStatsAggregator is a thread-safe statistics aggregator
+type concurrentStatsAggregator[T Number] struct {
+	synchronized[T, *runningStats[T]]
+}
+
+func (a *concurrentStatsAggregator[T]) AggregateSummary() Summary[T] {
+	a.lockAfterWait()
+	defer a.m.Unlock()
+
+	return a.items.summary()
+}
@@ -0,0 +1,354 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// errDropped is returned by store.add when the overflow policy silently
+	// discarded an item. It never reaches the collector.
+	errDropped = errors.New("item dropped")
+	// errFull is returned by store.add when OverflowBlock needs to wait for space
+	errFull = errors.New("aggregator is full")
+)
+
+// store is the slice storage shared by the built-in aggregators. It is not
+// synchronized; concurrent aggregators guard it with their own mutex.
+type store[T any] struct {
+	datas    []T
+	capacity int
+	sealed   bool
+	late     int
+	onLate   LateCollectHook[T]
+
+	maxItems int
+	policy   OverflowPolicy
+	dropped  int
+	// space is closed when space frees up or the store is sealed, waking
+	// collectors blocked by OverflowBlock. It is created by the first of them.
+	space chan struct{}
+	// awaiting counts the aggregations waiting for waiters, during which
+	// collectors give up rather than block
+	awaiting int
+	// shared is set once datas was handed out by view, so stored items are
+	// copied before being overwritten
+	shared bool
+}
+
+func newStore[T any](cfg *config) store[T] {
+	capacity := cfg.capacity
+	if cfg.maxItems > 0 {
+		capacity = min(capacity, cfg.maxItems)
+	}
+
+	return store[T]{
+		datas:    make([]T, 0, capacity),
+		capacity: capacity,
+		onLate:   typedOption[LateCollectHook[T]](cfg.lateHook, "late collect hook"),
+		maxItems: max(cfg.maxItems, 0),
+		policy:   cfg.policy,
+	}
+}
+
+// add stores data, or rejects it with ErrAggregatorClosed once sealed. When
+// the store is full it applies the overflow policy; with OverflowBlock it
+// returns errFull and the caller either waits for space or calls overflowed.
+func (s *store[T]) add(data T) error {
+	if s.sealed {
+		s.late++
+		return ErrAggregatorClosed
+	}
+
+	if s.maxItems > 0 && len(s.datas) >= s.maxItems {
+		switch s.policy {
+		case OverflowDropOldest:
+			s.own()
+			var zero T
+			s.datas[0] = zero
+			s.datas = append(s.datas[1:], data)
+			s.dropped++
+			return nil
+		case OverflowError:
+			return s.overflowed()
+		case OverflowBlock:
+			return errFull
+		default:
+			s.dropped++
+			return errDropped
+		}
+	}
+
+	s.datas = append(s.datas, data)
+	return nil
+}
+
+// overflowed gives up on an item that does not fit
+func (s *store[T]) overflowed() error {
+	s.dropped++
+	return ErrCapacityExceeded
+}
+
+// addWaiting is add for concurrent aggregators. With OverflowBlock it releases
+// m while waiting for space, until ctx is done. m must be held by the caller.
+func (s *store[T]) addWaiting(ctx context.Context, m *sync.Mutex, data T) error {
+	for {
+		err := s.add(data)
+		if err != errFull {
+			return err
+		}
+		if s.awaiting > 0 {
+			return s.overflowed()
+		}
+
+		if s.space == nil {
+			s.space = make(chan struct{})
+		}
+		space := s.space
+
+		m.Unlock()
+		select {
+		case <-space:
+			m.Lock()
+		case <-ctx.Done():
+			m.Lock()
+			s.dropped++
+			return ctx.Err()
+		}
+	}
+}
+
+// await waits for the waiters of a concurrent aggregator, then locks m, which
+// must not be held by the caller. A collector blocked by OverflowBlock while
+// holding a waiter would never return, since only Drain frees space, so while
+// there are waiters to wait for blocked collectors fail with
+// ErrCapacityExceeded instead.
+func (s *store[T]) await(m *sync.Mutex, wg *sync.WaitGroup, waiters *atomic.Int64) {
+	if s.policy != OverflowBlock || s.maxItems == 0 || waiters.Load() == 0 {
+		wg.Wait()
+		m.Lock()
+		return
+	}
+
+	m.Lock()
+	s.awaiting++
+	s.notifySpace()
+	m.Unlock()
+
+	wg.Wait()
+
+	m.Lock()
+	s.awaiting--
+}
+
+// notifySpace wakes every collector blocked by OverflowBlock
+func (s *store[T]) notifySpace() {
+	if s.space != nil {
+		close(s.space)
+		s.space = nil
+	}
+}
+
+// addSequential is add for sequential aggregators. Nothing can free space while
+// the only collector waits, so OverflowBlock fails with ErrCapacityExceeded.
+func (s *store[T]) addSequential(data T) error {
+	err := s.add(data)
+	if err == errFull {
+		return s.overflowed()
+	}
+
+	return err
+}
+
+// addMany stores a batch as add would store its items one by one, returning
+// how many leading items were accepted. Accepted items may still be evicted by
+// OverflowDropOldest.
+func (s *store[T]) addMany(items []T) (int, error) {
+	if s.sealed {
+		s.late += len(items)
+		return 0, ErrAggregatorClosed
+	}
+
+	room := len(items)
+	if s.maxItems > 0 {
+		room = max(s.maxItems-len(s.datas), 0)
+	}
+	if len(items) <= room {
+		s.datas = append(s.datas, items...)
+		return len(items), nil
+	}
+
+	switch s.policy {
+	case OverflowDropOldest:
+		s.dropOldest(items)
+		return len(items), nil
+	case OverflowError:
+		s.datas = append(s.datas, items[:room]...)
+		s.dropped += len(items) - room
+		return room, ErrCapacityExceeded
+	case OverflowBlock:
+		s.datas = append(s.datas, items[:room]...)
+		return room, errFull
+	default:
+		s.datas = append(s.datas, items[:room]...)
+		s.dropped += len(items) - room
+		return room, errDropped
+	}
+}
+
+// dropOldest appends items to a full store, evicting the oldest items so that
+// it keeps the last maxItems
+func (s *store[T]) dropOldest(items []T) {
+	s.own()
+	evicted := len(s.datas) + len(items) - s.maxItems
+	s.dropped += evicted
+
+	if len(items) >= s.maxItems {
+		clear(s.datas)
+		s.datas = append(s.datas[:0], items[len(items)-s.maxItems:]...)
+		return
+	}
+
+	clear(s.datas[:evicted])
+	s.datas = append(s.datas[evicted:], items...)
+}
+
+// addManyWaiting is addMany for concurrent aggregators. With OverflowBlock it
+// releases m while waiting for space for the rest of the batch, until ctx is
+// done. m must be held by the caller.
+func (s *store[T]) addManyWaiting(ctx context.Context, m *sync.Mutex, items []T) (int, error) {
+	accepted := 0
+	for {
+		n, err := s.addMany(items[accepted:])
+		accepted += n
+		if err != errFull {
+			return accepted, err
+		}
+		if s.awaiting > 0 {
+			s.dropped += len(items) - accepted
+			return accepted, ErrCapacityExceeded
+		}
+
+		if s.space == nil {
+			s.space = make(chan struct{})
+		}
+		space := s.space
+
+		m.Unlock()
+		select {
+		case <-space:
+			m.Lock()
+		case <-ctx.Done():
+			m.Lock()
+			s.dropped += len(items) - accepted
+			return accepted, ctx.Err()
+		}
+	}
+}
+
+// addManySequential is addMany for sequential aggregators, failing the rest of
+// the batch with ErrCapacityExceeded under OverflowBlock
+func (s *store[T]) addManySequential(items []T) (int, error) {
+	n, err := s.addMany(items)
+	if err == errFull {
+		s.dropped += len(items) - n
+		return n, ErrCapacityExceeded
+	}
+
+	return n, err
+}
+
+// settleMany is settle for a batch whose first accepted items were stored
+func (s *store[T]) settleMany(items []T, accepted int, err error) error {
+	switch err {
+	case errDropped:
+		return nil
+	case ErrAggregatorClosed:
+		if s.onLate != nil {
+			for _, item := range items[accepted:] {
+				invokeCallback(CollectCallback[T](s.onLate), item)
+			}
+		}
+	}
+
+	return err
+}
+
+// settle turns the outcome of add into the error returned to the collector,
+// reporting late items to the hook. Concurrent aggregators call it after
+// releasing their mutex.
+func (s *store[T]) settle(data T, err error) error {
+	switch err {
+	case errDropped:
+		return nil
+	case ErrAggregatorClosed:
+		if s.onLate != nil {
+			invokeCallback(CollectCallback[T](s.onLate), data)
+		}
+	}
+
+	return err
+}
+
+func (s *store[T]) seal() {
+	s.sealed = true
+	s.notifySpace()
+}
+
+func (s *store[T]) snapshot() []T {
+	return slices.Clone(s.datas)
+}
+
+// view returns the stored items without copying them. Later collects append
+// past them, and overwriting them copies them first, so the view stays valid.
+func (s *store[T]) view() []T {
+	s.shared = true
+	return s.datas[:len(s.datas):len(s.datas)]
+}
+
+// own copies the stored items if a view may still read them, before they are
+// overwritten or handed over
+func (s *store[T]) own() {
+	if s.shared {
+		s.datas = append(make([]T, 0, cap(s.datas)), s.datas...)
+		s.shared = false
+	}
+}
+
+// drain hands the stored items over to the caller and empties the store
+func (s *store[T]) drain() []T {
+	s.own()
+	items := s.datas
+	s.datas = make([]T, 0, s.capacity)
+	s.notifySpace()
+
+	return items
+}
+
+func (s *store[T]) info(kind Kind) AggregatorInfo {
+	return AggregatorInfo{
+		ElemType:     elemTypeName[T](),
+		Kind:         kind,
+		Len:          len(s.datas),
+		Cap:          cap(s.datas),
+		Sealed:       s.sealed,
+		LateCollects: s.late,
+		MaxItems:     s.maxItems,
+		Dropped:      s.dropped,
+	}
+}
+
+// invokeCallback calls callback with panic recovery, so a failing callback
+// never disrupts collection
+func invokeCallback[T any](callback CollectCallback[T], data T) {
+	defer func() {
+		if r := recover(); r != nil {
+			// Silently recover from callback panics to prevent disrupting collection
+		}
+	}()
+
+	callback(data)
+}
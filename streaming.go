@@ -3,10 +3,23 @@ package aggregator
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 )
 
 var _ ContextAggregator[any] = new(streamingAggregator[any])
-var _ ContextAggregator[any] = new(concurrentStreamingAggregator[any])
+var _ ConcurrentContextAggregator[any] = new(concurrentStreamingAggregator[any])
+var _ UnsafeAggregator[any] = new(streamingAggregator[any])
+var _ UnsafeAggregator[any] = new(concurrentStreamingAggregator[any])
+var _ SealableAggregator[any] = new(streamingAggregator[any])
+var _ SealableAggregator[any] = new(concurrentStreamingAggregator[any])
+var _ BoundedAggregator[any] = new(streamingAggregator[any])
+var _ BoundedAggregator[any] = new(concurrentStreamingAggregator[any])
+var _ inspector = new(streamingAggregator[any])
+var _ inspector = new(concurrentStreamingAggregator[any])
+var _ viewer[any] = new(streamingAggregator[any])
+var _ viewer[any] = new(concurrentStreamingAggregator[any])
+var _ FlushableAggregator[any] = new(concurrentStreamingAggregator[any])
+var _ anyContextCollector = new(concurrentStreamingAggregator[any])
 
 // CollectCallback is a function that is called whenever an item is collected
 type CollectCallback[T any] func(T)
@@ -15,119 +28,278 @@ type CollectCallback[T any] func(T)
 // function for each collected item. The callback is invoked synchronously during collection.
 // Use this for sequential data collection with real-time processing.
 func RegisterStreamingAggregator[T any](ctx context.Context, callback CollectCallback[T], keys ...string) context.Context {
-	agg := &streamingAggregator[T]{
-		datas:    make([]T, 0),
-		callback: callback,
-	}
-	ctxKey := buildContextKey(keys...)
-	return context.WithValue(ctx, ctxKey, agg)
+	return Register[T](ctx, WithCallback(callback), WithKey(keys...))
 }
 
 // RegisterStreamingAggregatorWithCapacity registers a streaming aggregator with capacity hint
 func RegisterStreamingAggregatorWithCapacity[T any](ctx context.Context, capacity int, callback CollectCallback[T], keys ...string) context.Context {
-	agg := &streamingAggregator[T]{
-		datas:    make([]T, 0, capacity),
-		callback: callback,
-	}
-	ctxKey := buildContextKey(keys...)
-	return context.WithValue(ctx, ctxKey, agg)
+	return Register[T](ctx, WithCapacity(capacity), WithCallback(callback), WithKey(keys...))
 }
 
 // RegisterConcurrentStreamingAggregator registers a thread-safe streaming aggregator
 // that calls a callback function for each collected item. The callback is invoked
 // synchronously during collection with mutex protection.
 func RegisterConcurrentStreamingAggregator[T any](ctx context.Context, callback CollectCallback[T], keys ...string) context.Context {
-	agg := &concurrentStreamingAggregator[T]{
-		m:        &sync.Mutex{},
-		wg:       &sync.WaitGroup{},
-		datas:    make([]T, 0),
-		callback: callback,
-	}
-	ctxKey := buildContextKey(keys...)
-	return context.WithValue(ctx, ctxKey, agg)
+	return Register[T](ctx, WithConcurrency(), WithCallback(callback), WithKey(keys...))
 }
 
 // RegisterConcurrentStreamingAggregatorWithCapacity registers a thread-safe streaming aggregator with capacity hint
 func RegisterConcurrentStreamingAggregatorWithCapacity[T any](ctx context.Context, capacity int, callback CollectCallback[T], keys ...string) context.Context {
-	agg := &concurrentStreamingAggregator[T]{
-		m:        &sync.Mutex{},
-		wg:       &sync.WaitGroup{},
-		datas:    make([]T, 0, capacity),
-		callback: callback,
-	}
-	ctxKey := buildContextKey(keys...)
-	return context.WithValue(ctx, ctxKey, agg)
+	return Register[T](ctx, WithConcurrency(), WithCapacity(capacity), WithCallback(callback), WithKey(keys...))
 }
 
 // streamingAggregator is a sequential aggregator with callback support
 type streamingAggregator[T any] struct {
-	datas    []T
-	callback CollectCallback[T]
+	store[T]
+	callbacks[T]
+	// buf hands single items to the batch callback
+	buf [1]T
 }
 
 func (a *streamingAggregator[T]) Collect(data T) {
-	// Call callback first (with panic recovery)
-	if a.callback != nil {
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					// Silently recover from callback panics to prevent disrupting collection
-				}
-			}()
-			a.callback(data)
-		}()
+	_ = a.TryCollect(data)
+}
+
+func (a *streamingAggregator[T]) TryCollect(data T) error {
+	// Store data for later aggregation, only stored items reach the callback
+	err := a.addSequential(data)
+	if err == nil {
+		a.deliver(data, &a.buf)
 	}
 
-	// Store data for later aggregation
-	a.datas = append(a.datas, data)
+	return a.settle(data, err)
+}
+
+func (a *streamingAggregator[T]) collectMany(_ context.Context, items []T) error {
+	n, err := a.addManySequential(items)
+	a.deliverMany(items[:n])
+
+	return a.settleMany(items, n, err)
 }
 
 func (a *streamingAggregator[T]) Aggregate() []T {
+	return a.snapshot()
+}
+
+func (a *streamingAggregator[T]) AggregateUnsafe() []T {
 	return a.datas
 }
 
-// concurrentStreamingAggregator is a thread-safe aggregator with callback support
+func (a *streamingAggregator[T]) AggregateWithDropped() ([]T, int) {
+	return a.snapshot(), a.dropped
+}
+
+func (a *streamingAggregator[T]) Drain() []T {
+	return a.drain()
+}
+
+func (a *streamingAggregator[T]) Seal() {
+	a.seal()
+}
+
+func (a *streamingAggregator[T]) AggregateAndSeal() []T {
+	a.seal()
+	return a.snapshot()
+}
+
+func (a *streamingAggregator[T]) LateCollects() int {
+	return a.late
+}
+
+func (a *streamingAggregator[T]) inspect() AggregatorInfo {
+	return a.info(KindStreaming)
+}
+
+// concurrentStreamingAggregator is a thread-safe aggregator with callback
+// support. With async set, callbacks run on its workers instead of under m.
 type concurrentStreamingAggregator[T any] struct {
-	m        *sync.Mutex
-	wg       *sync.WaitGroup
-	datas    []T
-	callback CollectCallback[T]
+	m       *sync.Mutex
+	wg      *sync.WaitGroup
+	waiters atomic.Int64
+	store[T]
+	callbacks[T]
+	// buf hands single items to the batch callback, guarded by m
+	buf   [1]T
+	async *dispatcher[T]
 }
 
 func (a *concurrentStreamingAggregator[T]) Collect(data T) {
+	_ = a.TryCollect(data)
+}
+
+func (a *concurrentStreamingAggregator[T]) TryCollect(data T) error {
+	return a.collectContext(context.Background(), data)
+}
+
+func (a *concurrentStreamingAggregator[T]) collectContext(ctx context.Context, data T) error {
+	var waiting *task[T]
 	a.m.Lock()
-	defer a.m.Unlock()
+	err := a.addWaiting(ctx, a.m, data)
+	if err == nil {
+		waiting, err = a.dispatch(data)
+	}
+	a.m.Unlock()
+
+	// Waiting for room in the callback queue never holds the mutex
+	if waiting != nil {
+		err = a.async.await(ctx, waiting)
+	}
+
+	return a.settle(data, err)
+}
+
+func (a *concurrentStreamingAggregator[T]) collectContextAny(ctx context.Context, data any) error {
+	return a.collectContext(ctx, data.(T))
+}
+
+func (a *concurrentStreamingAggregator[T]) collectMany(ctx context.Context, items []T) error {
+	a.m.Lock()
+	n, err := a.addManyWaiting(ctx, a.m, items)
+	waiting, dispatchErr := a.dispatchMany(items[:n])
+	if dispatchErr != nil && (err == nil || err == errDropped) {
+		err = dispatchErr
+	}
+	a.m.Unlock()
+
+	for _, t := range waiting {
+		if waitErr := a.async.await(ctx, t); waitErr != nil && (err == nil || err == errDropped) {
+			err = waitErr
+		}
+	}
+
+	return a.settleMany(items, n, err)
+}
+
+// dispatch hands a stored item to the callbacks, or queues it for the workers.
+// It returns the task to await if the queue is full.
+func (a *concurrentStreamingAggregator[T]) dispatch(data T) (*task[T], error) {
+	if a.async == nil {
+		a.deliver(data, &a.buf)
+		return nil, nil
+	}
+
+	return a.async.dispatch(data)
+}
+
+func (a *concurrentStreamingAggregator[T]) dispatchMany(items []T) ([]*task[T], error) {
+	if a.async == nil {
+		a.deliverMany(items)
+		return nil, nil
+	}
 
-	// Call callback first (with panic recovery)
-	if a.callback != nil {
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					// Silently recover from callback panics to prevent disrupting collection
-				}
-			}()
-			a.callback(data)
-		}()
+	return a.async.dispatchMany(items)
+}
+
+// Flush waits for the queued callbacks, if callbacks run asynchronously
+func (a *concurrentStreamingAggregator[T]) Flush(ctx context.Context) error {
+	if a.async == nil {
+		return nil
+	}
+
+	return a.async.flush(ctx)
+}
+
+// Close seals the aggregator, then waits for its workers to run the queued
+// callbacks and exit, if callbacks run asynchronously
+func (a *concurrentStreamingAggregator[T]) Close(ctx context.Context) error {
+	a.Seal()
+	if a.async == nil {
+		return nil
 	}
 
-	// Store data for later aggregation
-	a.datas = append(a.datas, data)
+	return a.async.wait(ctx)
 }
 
 func (a *concurrentStreamingAggregator[T]) Aggregate() []T {
 	// Always call Wait before lock mutex for not cause deadlock
-	a.wg.Wait()
+	a.await(a.m, a.wg, &a.waiters)
+	defer a.m.Unlock()
 
-	a.m.Lock()
+	return a.snapshot()
+}
+
+func (a *concurrentStreamingAggregator[T]) AggregateUnsafe() []T {
+	a.await(a.m, a.wg, &a.waiters)
 	defer a.m.Unlock()
 
 	return a.datas
 }
 
+func (a *concurrentStreamingAggregator[T]) view() []T {
+	a.await(a.m, a.wg, &a.waiters)
+	defer a.m.Unlock()
+
+	return a.store.view()
+}
+
+func (a *concurrentStreamingAggregator[T]) AggregateWithDropped() ([]T, int) {
+	a.await(a.m, a.wg, &a.waiters)
+	defer a.m.Unlock()
+
+	return a.snapshot(), a.dropped
+}
+
+func (a *concurrentStreamingAggregator[T]) Drain() []T {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	return a.drain()
+}
+
+func (a *concurrentStreamingAggregator[T]) Seal() {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	a.sealAndClose()
+}
+
+func (a *concurrentStreamingAggregator[T]) AggregateAndSeal() []T {
+	a.await(a.m, a.wg, &a.waiters)
+	defer a.m.Unlock()
+
+	a.sealAndClose()
+	return a.snapshot()
+}
+
+// sealAndClose seals the store and lets the workers stop once the queued
+// callbacks have run. The caller must hold m.
+func (a *concurrentStreamingAggregator[T]) sealAndClose() {
+	a.seal()
+	if a.async != nil {
+		a.async.close()
+	}
+}
+
+func (a *concurrentStreamingAggregator[T]) LateCollects() int {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	return a.late
+}
+
 func (a *concurrentStreamingAggregator[T]) AddWait() {
+	a.waiters.Add(1)
 	a.wg.Add(1)
 }
 
 func (a *concurrentStreamingAggregator[T]) Done() {
+	a.waiters.Add(-1)
 	a.wg.Done()
 }
+
+func (a *concurrentStreamingAggregator[T]) inspect() AggregatorInfo {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	kind := KindConcurrentStreaming
+	if a.async != nil {
+		kind = KindAsyncStreaming
+	}
+
+	info := a.info(kind)
+	info.Waiters = int(a.waiters.Load())
+	if a.async != nil {
+		info.SkippedCallbacks = a.async.skippedCallbacks()
+	}
+	return info
+}
@@ -0,0 +1,116 @@
+package aggregator_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+type validationError struct {
+	field string
+}
+
+func (e *validationError) Error() string {
+	return "invalid " + e.field
+}
+
+type event interface {
+	Name() string
+}
+
+type userCreated struct{}
+
+func (userCreated) Name() string { return "user.created" }
+
+func TestAssignable_CollectConcreteIntoInterfaceAggregator(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregator[error](context.Background())
+
+	verr := &validationError{field: "email"}
+	assert.NoError(t, aggregator.Collect(ctx, verr))
+	assert.NoError(t, aggregator.Collect(ctx, errors.New("plain")))
+
+	errs, err := aggregator.Aggregate[error](ctx)
+	assert.NoError(t, err)
+	assert.Len(t, errs, 2)
+	assert.Same(t, verr, errs[0])
+}
+
+func TestAssignable_CollectWithKey(t *testing.T) {
+	ctx := aggregator.RegisterConcurrentContextAggregator[event](context.Background(), "events")
+
+	assert.NoError(t, aggregator.Collect(ctx, userCreated{}, "events"))
+
+	events, err := aggregator.Aggregate[event](ctx, "events")
+	assert.NoError(t, err)
+	assert.Equal(t, []event{userCreated{}}, events)
+}
+
+func TestAssignable_PicksMatchingInterface(t *testing.T) {
+	ctx := context.Background()
+	ctx = aggregator.RegisterBaseContextAggregator[error](ctx)
+	ctx = aggregator.RegisterBaseContextAggregator[event](ctx)
+	ctx = aggregator.RegisterBaseContextAggregator[fmt.Stringer](ctx)
+
+	assert.NoError(t, aggregator.Collect(ctx, &validationError{field: "name"}))
+	assert.NoError(t, aggregator.Collect(ctx, userCreated{}))
+
+	errs, _ := aggregator.Aggregate[error](ctx)
+	events, _ := aggregator.Aggregate[event](ctx)
+	stringers, _ := aggregator.Aggregate[fmt.Stringer](ctx)
+	assert.Len(t, errs, 1)
+	assert.Len(t, events, 1)
+	assert.Empty(t, stringers)
+}
+
+func TestAssignable_NothingFits(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregator[error](context.Background())
+	err := aggregator.Collect(ctx, userCreated{})
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+
+	ctx = aggregator.RegisterBaseContextAggregator[error](context.Background(), "errors")
+	err = aggregator.Collect(ctx, userCreated{}, "errors")
+	assert.Equal(t, aggregator.ErrInvalidType, err)
+}
+
+func TestAssignable_CollectAs(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregator[error](context.Background())
+
+	assert.NoError(t, aggregator.CollectAs[error](ctx, &validationError{field: "age"}))
+
+	err := aggregator.CollectAs[error](ctx, "not an error")
+	assert.ErrorIs(t, err, aggregator.ErrInvalidType)
+
+	var mismatch *aggregator.TypeMismatchError
+	assert.ErrorAs(t, err, &mismatch)
+	assert.Contains(t, err.Error(), "string is not assignable to error")
+
+	errs, err := aggregator.Aggregate[error](ctx)
+	assert.NoError(t, err)
+	assert.Len(t, errs, 1)
+}
+
+func TestAssignable_BlockingCollectRespectsCancellation(t *testing.T) {
+	for _, keys := range [][]string{nil, {"errors"}} {
+		t.Run(fmt.Sprint(keys), func(t *testing.T) {
+			ctx := aggregator.Register[error](context.Background(),
+				aggregator.WithConcurrency(),
+				aggregator.WithMaxItems(1, aggregator.OverflowBlock),
+				aggregator.WithKey(keys...),
+			)
+			assert.NoError(t, aggregator.Collect(ctx, &validationError{field: "email"}, keys...))
+
+			timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+			err := aggregator.Collect(timeoutCtx, &validationError{field: "name"}, keys...)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+			errs, _ := aggregator.Aggregate[error](ctx, keys...)
+			assert.Len(t, errs, 1)
+		})
+	}
+}
@@ -0,0 +1,468 @@
+package aggregator_test
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+// recorder records the items its callback sees
+type recorder struct {
+	m     sync.Mutex
+	items []int
+}
+
+func (r *recorder) record(item int) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.items = append(r.items, item)
+}
+
+func (r *recorder) seen() []int {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return slices.Clone(r.items)
+}
+
+// checkGoroutines fails t if goroutines started by the test, such as workers,
+// are still running once it returned
+func checkGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		assert.LessOrEqual(t, runtime.NumGoroutine(), before, "goroutines leaked")
+	})
+}
+
+// gated returns a callback recording items once the gate is opened, and a
+// channel receiving every item as its callback starts
+func gated(r *recorder, gate <-chan struct{}) (func(int), <-chan int) {
+	started := make(chan int, 100)
+	return func(item int) {
+		started <- item
+		<-gate
+		r.record(item)
+	}, started
+}
+
+func TestAsync_CollectDoesNotWaitForCallbacks(t *testing.T) {
+	checkGoroutines(t)
+	gate := make(chan struct{})
+	r := &recorder{}
+	callback, _ := gated(r, gate)
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(callback),
+		aggregator.WithAsyncCallbacks(1, 10, aggregator.OverflowBlock),
+	)
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, aggregator.Collect(ctx, i))
+	}
+	assert.Empty(t, r.seen())
+
+	close(gate)
+	assert.NoError(t, aggregator.Flush[int](ctx))
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, r.seen())
+
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, results)
+	assert.NoError(t, aggregator.Close[int](ctx))
+}
+
+func TestAsync_Workers(t *testing.T) {
+	checkGoroutines(t)
+	r := &recorder{}
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(r.record),
+		aggregator.WithAsyncCallbacks(4, 0, aggregator.OverflowBlock),
+	)
+
+	for i := 0; i < 100; i++ {
+		ctx, done := aggregator.WaitFuncOf[int](ctx)
+		go func(i int) {
+			defer done()
+			_ = aggregator.Collect(ctx, i)
+		}(i)
+	}
+
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Len(t, results, 100)
+	assert.NoError(t, aggregator.Close[int](ctx))
+	assert.ElementsMatch(t, intRange(100), r.seen())
+}
+
+func TestAsync_OrderKey(t *testing.T) {
+	checkGoroutines(t)
+	var m sync.Mutex
+	perKey := make(map[int][]int)
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(func(item int) {
+			time.Sleep(time.Duration(item%3) * time.Microsecond)
+			m.Lock()
+			perKey[item%10] = append(perKey[item%10], item)
+			m.Unlock()
+		}),
+		aggregator.WithAsyncCallbacks(4, 64, aggregator.OverflowBlock),
+		aggregator.WithOrderKey(func(item int) int { return item % 10 }),
+	)
+
+	for i := 0; i < 500; i++ {
+		assert.NoError(t, aggregator.Collect(ctx, i))
+	}
+	assert.NoError(t, aggregator.CollectMany(ctx, []int{500, 501, 502}))
+	assert.NoError(t, aggregator.Close[int](ctx))
+
+	assert.Len(t, perKey, 10)
+	for key, items := range perKey {
+		assert.True(t, slices.IsSorted(items), "key %d out of order", key)
+		assert.Equal(t, key, items[0])
+	}
+}
+
+func TestAsync_OrderKeyTypeMismatch(t *testing.T) {
+	assert.Panics(t, func() {
+		aggregator.Register[int](context.Background(),
+			aggregator.WithCallback(func(int) {}),
+			aggregator.WithAsyncCallbacks(2, 0, aggregator.OverflowBlock),
+			aggregator.WithOrderKey(func(s string) string { return s }),
+		)
+	})
+
+	// Checked even when it does not apply
+	assert.Panics(t, func() {
+		aggregator.Register[int](context.Background(),
+			aggregator.WithOrderKey(func(s string) string { return s }),
+		)
+	})
+}
+
+func TestAsync_BatchCallback(t *testing.T) {
+	checkGoroutines(t)
+	var m sync.Mutex
+	var batches [][]int
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithBatchCallback(func(batch []int) {
+			m.Lock()
+			batches = append(batches, slices.Clone(batch))
+			m.Unlock()
+		}),
+		aggregator.WithAsyncCallbacks(1, 0, aggregator.OverflowBlock),
+	)
+
+	items := []int{1, 2, 3}
+	assert.NoError(t, aggregator.CollectMany(ctx, items))
+	// The caller owns its batch
+	items[0] = 100
+	assert.NoError(t, aggregator.Collect(ctx, 4))
+	assert.NoError(t, aggregator.Close[int](ctx))
+
+	assert.Equal(t, [][]int{{1, 2, 3}, {4}}, batches)
+}
+
+func TestAsync_Backpressure(t *testing.T) {
+	tests := []struct {
+		policy aggregator.OverflowPolicy
+		err    error
+		seen   []int
+	}{
+		{policy: aggregator.OverflowDropNewest, seen: []int{0, 1}},
+		{policy: aggregator.OverflowDropOldest, seen: []int{0, 2}},
+		{policy: aggregator.OverflowError, err: aggregator.ErrQueueFull, seen: []int{0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.policy), func(t *testing.T) {
+			checkGoroutines(t)
+			gate := make(chan struct{})
+			r := &recorder{}
+			callback, started := gated(r, gate)
+			ctx := aggregator.Register[int](context.Background(),
+				aggregator.WithCallback(callback),
+				aggregator.WithAsyncCallbacks(1, 1, tt.policy),
+			)
+
+			// The worker is busy with 0 and 1 fills the queue
+			assert.NoError(t, aggregator.Collect(ctx, 0))
+			<-started
+			assert.NoError(t, aggregator.Collect(ctx, 1))
+
+			err := aggregator.Collect(ctx, 2)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, aggregator.ErrCallbackSkipped)
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			close(gate)
+			assert.NoError(t, aggregator.Close[int](ctx))
+			assert.Equal(t, tt.seen, r.seen())
+
+			// Items are stored whatever happens to their callback
+			results, _ := aggregator.Aggregate[int](ctx)
+			assert.Equal(t, []int{0, 1, 2}, results)
+			assert.Equal(t, 1, aggregator.Inspect(ctx)[0].SkippedCallbacks)
+		})
+	}
+}
+
+func TestAsync_DropOldestDropsWholeBatch(t *testing.T) {
+	checkGoroutines(t)
+	gate := make(chan struct{})
+	r := &recorder{}
+	callback, started := gated(r, gate)
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(callback),
+		aggregator.WithAsyncCallbacks(1, 1, aggregator.OverflowDropOldest),
+	)
+
+	// The worker is busy with 0 and the batch fills the queue as a single task
+	assert.NoError(t, aggregator.Collect(ctx, 0))
+	<-started
+	assert.NoError(t, aggregator.CollectMany(ctx, []int{1, 2, 3}))
+	assert.NoError(t, aggregator.Collect(ctx, 4))
+
+	close(gate)
+	assert.NoError(t, aggregator.Close[int](ctx))
+	assert.Equal(t, []int{0, 4}, r.seen())
+	assert.Equal(t, 3, aggregator.Inspect(ctx)[0].SkippedCallbacks)
+}
+
+func TestAsync_BlockUntilRoom(t *testing.T) {
+	checkGoroutines(t)
+	gate := make(chan struct{})
+	r := &recorder{}
+	callback, started := gated(r, gate)
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(callback),
+		aggregator.WithAsyncCallbacks(1, 1, aggregator.OverflowBlock),
+	)
+
+	assert.NoError(t, aggregator.Collect(ctx, 0))
+	<-started
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+
+	collected := make(chan error)
+	go func() {
+		collected <- aggregator.Collect(ctx, 2)
+	}()
+
+	select {
+	case <-collected:
+		t.Fatal("collect should block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(gate)
+	assert.NoError(t, <-collected)
+	assert.NoError(t, aggregator.Close[int](ctx))
+	assert.Equal(t, []int{0, 1, 2}, r.seen())
+}
+
+func TestAsync_BlockedCollectorDoesNotHoldLock(t *testing.T) {
+	checkGoroutines(t)
+	gate := make(chan struct{})
+	r := &recorder{}
+	callback, started := gated(r, gate)
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(callback),
+		aggregator.WithAsyncCallbacks(1, 1, aggregator.OverflowBlock),
+	)
+
+	assert.NoError(t, aggregator.Collect(ctx, 0))
+	<-started
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+
+	collected := make(chan error)
+	go func() {
+		collected <- aggregator.Collect(ctx, 2)
+	}()
+
+	// The blocked collector already stored its item and let go of the lock
+	assert.Eventually(t, func() bool {
+		results, _ := aggregator.Aggregate[int](ctx)
+		return len(results) == 3
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 3, aggregator.Inspect(ctx)[0].Len)
+
+	done := make(chan error)
+	go func() {
+		done <- aggregator.Close[int](ctx)
+	}()
+	close(gate)
+	assert.NoError(t, <-collected)
+	assert.NoError(t, <-done)
+	assert.Equal(t, []int{0, 1, 2}, r.seen())
+}
+
+func TestAsync_BlockRespectsCancellation(t *testing.T) {
+	checkGoroutines(t)
+	gate := make(chan struct{})
+	r := &recorder{}
+	callback, started := gated(r, gate)
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(callback),
+		aggregator.WithAsyncCallbacks(1, 1, aggregator.OverflowBlock),
+	)
+
+	assert.NoError(t, aggregator.Collect(ctx, 0))
+	<-started
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+
+	cancelCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	err := aggregator.Collect(cancelCtx, 2)
+	assert.ErrorIs(t, err, aggregator.ErrCallbackSkipped)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The item was stored, only its callback is skipped
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, []int{0, 1, 2}, results)
+	assert.Equal(t, 1, aggregator.Inspect(ctx)[0].SkippedCallbacks)
+
+	close(gate)
+	assert.NoError(t, aggregator.Close[int](ctx))
+	assert.Equal(t, []int{0, 1}, r.seen())
+}
+
+func TestAsync_FlushRespectsCancellation(t *testing.T) {
+	checkGoroutines(t)
+	gate := make(chan struct{})
+	r := &recorder{}
+	callback, _ := gated(r, gate)
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(callback),
+		aggregator.WithAsyncCallbacks(1, 0, aggregator.OverflowBlock),
+	)
+	assert.NoError(t, aggregator.Collect(ctx, 0))
+
+	flushCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, aggregator.Flush[int](flushCtx), context.DeadlineExceeded)
+
+	closeCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, aggregator.Close[int](closeCtx), context.DeadlineExceeded)
+
+	close(gate)
+	assert.NoError(t, aggregator.Close[int](ctx))
+}
+
+func TestAsync_SealRunsQueuedCallbacks(t *testing.T) {
+	checkGoroutines(t)
+	gate := make(chan struct{})
+	r := &recorder{}
+	callback, _ := gated(r, gate)
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(callback),
+		aggregator.WithAsyncCallbacks(2, 0, aggregator.OverflowBlock),
+	)
+
+	assert.NoError(t, aggregator.CollectMany(ctx, []int{1, 2, 3}))
+	assert.NoError(t, aggregator.Collect(ctx, 4))
+	results, err := aggregator.AggregateAndSeal[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4}, results)
+	assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, 5))
+
+	close(gate)
+	assert.NoError(t, aggregator.Flush[int](ctx))
+	assert.ElementsMatch(t, []int{1, 2, 3, 4}, r.seen())
+	assert.NoError(t, aggregator.Close[int](ctx))
+}
+
+func TestAsync_StopsWhenContextDone(t *testing.T) {
+	checkGoroutines(t)
+	gate := make(chan struct{})
+	r := &recorder{}
+	callback, started := gated(r, gate)
+
+	parent, cancel := context.WithCancel(context.Background())
+	ctx := aggregator.Register[int](parent,
+		aggregator.WithCallback(callback),
+		aggregator.WithAsyncCallbacks(1, 1, aggregator.OverflowBlock),
+	)
+
+	assert.NoError(t, aggregator.Collect(ctx, 0))
+	<-started
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+
+	// Collectors whose own context is not done
+	live := context.WithoutCancel(ctx)
+	blocked := make(chan error)
+	go func() {
+		blocked <- aggregator.Collect(live, 2)
+	}()
+
+	// The collector blocked on the full queue is released and its callback skipped
+	cancel()
+	assert.NoError(t, <-blocked)
+	assert.Equal(t, context.Canceled, aggregator.Flush[int](live))
+
+	assert.NoError(t, aggregator.Collect(live, 3))
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, []int{0, 1, 2, 3}, results)
+	assert.Empty(t, r.seen())
+
+	// The worker busy with 0 exits once its callback returns
+	close(gate)
+	assert.NoError(t, aggregator.Close[int](live))
+}
+
+func TestAsync_Inspect(t *testing.T) {
+	checkGoroutines(t)
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(func(int) {}),
+		aggregator.WithAsyncCallbacks(2, 0, aggregator.OverflowBlock),
+	)
+	defer func() { assert.NoError(t, aggregator.Close[int](ctx)) }()
+
+	infos := aggregator.Inspect(ctx)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, aggregator.KindAsyncStreaming, infos[0].Kind)
+}
+
+func TestAsync_CloseNew(t *testing.T) {
+	checkGoroutines(t)
+	r := &recorder{}
+	agg := aggregator.New[int](
+		aggregator.WithCallback(r.record),
+		aggregator.WithAsyncCallbacks(4, 0, aggregator.OverflowBlock),
+	).(aggregator.FlushableAggregator[int])
+
+	for i := 0; i < 10; i++ {
+		agg.Collect(i)
+	}
+	assert.NoError(t, agg.Close(context.Background()))
+	assert.ElementsMatch(t, intRange(10), r.seen())
+}
+
+func TestClose_Synchronous(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithCallback(func(int) {}))
+	assert.NoError(t, aggregator.Close[int](ctx))
+	assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, 1))
+
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, aggregator.Close[int](context.Background()))
+}
+
+func TestFlush_Synchronous(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithConcurrency(), aggregator.WithCallback(func(int) {}))
+	assert.NoError(t, aggregator.Flush[int](ctx))
+
+	ctx = aggregator.Register[int](context.Background())
+	assert.NoError(t, aggregator.Flush[int](ctx))
+
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, aggregator.Flush[int](context.Background()))
+}
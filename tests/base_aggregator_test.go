@@ -26,8 +26,9 @@ func TestBaseContextAggregator_CollectNotFoundAggregator(t *testing.T) {
 }
 
 func TestBaseContextAggregator_CollectInvalidType(t *testing.T) {
-	ctx := aggregator.RegisterBaseContextAggregator[int](context.Background())
-	err := funcBaseCollecInt32(ctx)
+	key := "test"
+	ctx := aggregator.RegisterBaseContextAggregator[int](context.Background(), key)
+	err := funcBaseCollecInt32(ctx, key)
 	assert.Equal(t, err, aggregator.ErrInvalidType)
 }
 
@@ -38,11 +39,12 @@ func TestBaseContextAggregator_AggregateNotFoundAggregator(t *testing.T) {
 }
 
 func TestBaseContextAggregator_AggregateInvalidType(t *testing.T) {
-	ctx := aggregator.RegisterBaseContextAggregator[int32](context.Background())
-	err := funcBaseCollecInt32(ctx)
+	key := "test"
+	ctx := aggregator.RegisterBaseContextAggregator[int32](context.Background(), key)
+	err := funcBaseCollecInt32(ctx, key)
 	assert.Nil(t, err)
 
-	result, err := aggregator.Aggregate[int](ctx)
+	result, err := aggregator.Aggregate[int](ctx, key)
 	assert.Nil(t, result)
 	assert.Equal(t, err, aggregator.ErrInvalidType)
 }
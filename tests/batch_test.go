@@ -0,0 +1,408 @@
+package aggregator_test
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestCollectMany(t *testing.T) {
+	flavors := map[string][]aggregator.Option{
+		"sequential":          nil,
+		"concurrent":          {aggregator.WithConcurrency()},
+		"streaming":           {aggregator.WithCallback(func(int) {})},
+		"concurrentStreaming": {aggregator.WithConcurrency(), aggregator.WithCallback(func(int) {})},
+		"sharded":             {aggregator.WithShards(4)},
+	}
+
+	for name, opts := range flavors {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			assert.NoError(t, aggregator.Collect(ctx, 0))
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{1, 2, 3}))
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{}))
+			assert.NoError(t, aggregator.Collect(ctx, 4))
+
+			results, err := aggregator.Aggregate[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{0, 1, 2, 3, 4}, results)
+		})
+	}
+}
+
+func TestCollectMany_NotFound(t *testing.T) {
+	err := aggregator.CollectMany(context.Background(), []int{1})
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithKey("a"))
+	err = aggregator.CollectMany(ctx, []int{1}, "b")
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+}
+
+func TestCollectMany_CallerOwnsItems(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			items := []int{1, 2, 3}
+			assert.NoError(t, aggregator.CollectMany(ctx, items))
+			items[0] = 100
+
+			results, _ := aggregator.Aggregate[int](ctx)
+			assert.Equal(t, []int{1, 2, 3}, results)
+		})
+	}
+}
+
+func TestCollectMany_DropNewest(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, aggregator.WithMaxItems(3, aggregator.OverflowDropNewest))
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			assert.NoError(t, aggregator.Collect(ctx, 1))
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{2, 3, 4, 5}))
+
+			results, dropped, err := aggregator.AggregateWithDropped[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{1, 2, 3}, results)
+			assert.Equal(t, 2, dropped)
+		})
+	}
+}
+
+func TestCollectMany_DropOldest(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, aggregator.WithMaxItems(3, aggregator.OverflowDropOldest))
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{1, 2}))
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{3, 4}))
+
+			results, dropped, _ := aggregator.AggregateWithDropped[int](ctx)
+			assert.Equal(t, []int{2, 3, 4}, results)
+			assert.Equal(t, 1, dropped)
+
+			// A batch larger than the limit keeps its own last items
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{5, 6, 7, 8, 9}))
+
+			results, dropped, _ = aggregator.AggregateWithDropped[int](ctx)
+			assert.Equal(t, []int{7, 8, 9}, results)
+			assert.Equal(t, 6, dropped)
+		})
+	}
+}
+
+func TestCollectMany_Error(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, aggregator.WithMaxItems(3, aggregator.OverflowError))
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			err := aggregator.CollectMany(ctx, []int{1, 2, 3, 4})
+			assert.Equal(t, aggregator.ErrCapacityExceeded, err)
+
+			results, dropped, _ := aggregator.AggregateWithDropped[int](ctx)
+			assert.Equal(t, []int{1, 2, 3}, results)
+			assert.Equal(t, 1, dropped)
+		})
+	}
+}
+
+func TestCollectMany_BlockUntilDrain(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithConcurrency(),
+		aggregator.WithMaxItems(2, aggregator.OverflowBlock),
+	)
+
+	collected := make(chan error)
+	go func() {
+		collected <- aggregator.CollectMany(ctx, []int{1, 2, 3})
+	}()
+
+	select {
+	case <-collected:
+		t.Fatal("collect should block while the aggregator is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	drained, err := aggregator.Drain[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, drained)
+
+	assert.NoError(t, <-collected)
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, []int{3}, results)
+}
+
+func TestCollectMany_BlockRespectsCancellation(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithConcurrency(),
+		aggregator.WithMaxItems(2, aggregator.OverflowBlock),
+	)
+
+	cancelCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	err := aggregator.CollectMany(cancelCtx, []int{1, 2, 3, 4})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	results, dropped, _ := aggregator.AggregateWithDropped[int](ctx)
+	assert.Equal(t, []int{1, 2}, results)
+	assert.Equal(t, 2, dropped)
+}
+
+func TestCollectMany_BlockOnSequentialAggregator(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithMaxItems(2, aggregator.OverflowBlock),
+	)
+
+	err := aggregator.CollectMany(ctx, []int{1, 2, 3})
+	assert.Equal(t, aggregator.ErrCapacityExceeded, err)
+
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, []int{1, 2}, results)
+}
+
+func TestCollectMany_Callbacks(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			var items []int
+			var batches [][]int
+			opts = append(opts,
+				aggregator.WithCallback(func(item int) { items = append(items, item) }),
+				aggregator.WithBatchCallback(func(batch []int) { batches = append(batches, slices.Clone(batch)) }),
+				aggregator.WithMaxItems(4, aggregator.OverflowDropNewest),
+			)
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			assert.NoError(t, aggregator.Collect(ctx, 1))
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{2, 3}))
+			// Only the stored items are delivered
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{4, 5, 6}))
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{7}))
+
+			assert.Equal(t, []int{1, 2, 3, 4}, items)
+			assert.Equal(t, [][]int{{1}, {2, 3}, {4}}, batches)
+		})
+	}
+}
+
+func TestCollect_BatchCallbackDoesNotAllocate(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			total := 0
+			opts = append(opts,
+				aggregator.WithCapacity(1000),
+				aggregator.WithBatchCallback(func(batch []int) { total += batch[0] }),
+			)
+			agg := aggregator.New[int](opts...)
+
+			allocs := testing.AllocsPerRun(100, func() { agg.Collect(1) })
+			assert.Zero(t, allocs)
+			assert.Equal(t, 101, total)
+		})
+	}
+}
+
+func TestCollectMany_BatchCallbackOnly(t *testing.T) {
+	var batches atomic.Int32
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithBatchCallback(func([]int) { batches.Add(1) }),
+	)
+
+	assert.NoError(t, aggregator.CollectMany(ctx, []int{1, 2, 3}))
+	assert.Equal(t, int32(1), batches.Load())
+
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, []int{1, 2, 3}, results)
+}
+
+func TestCollectMany_BatchCallbackTypeMismatch(t *testing.T) {
+	assert.Panics(t, func() {
+		aggregator.Register[int](context.Background(),
+			aggregator.WithBatchCallback(func([]string) {}),
+		)
+	})
+}
+
+func TestCollectMany_Sealed(t *testing.T) {
+	flavors := concurrencyFlavors()
+	flavors["sharded"] = []aggregator.Option{aggregator.WithShards(4)}
+
+	for name, opts := range flavors {
+		t.Run(name, func(t *testing.T) {
+			var late []int
+			opts = append(opts, aggregator.WithLateCollectHook(func(item int) { late = append(late, item) }))
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{1, 2}))
+			assert.NoError(t, aggregator.Seal[int](ctx))
+
+			err := aggregator.CollectMany(ctx, []int{3, 4})
+			assert.Equal(t, aggregator.ErrAggregatorClosed, err)
+			assert.Equal(t, []int{3, 4}, late)
+
+			n, _ := aggregator.LateCollects[int](ctx)
+			assert.Equal(t, 2, n)
+		})
+	}
+}
+
+func TestCollectMany_SpecialAggregators(t *testing.T) {
+	special := map[string]struct {
+		register func(context.Context, ...aggregator.Option) context.Context
+		want     []int
+	}{
+		"ring": {
+			register: func(ctx context.Context, opts ...aggregator.Option) context.Context {
+				return aggregator.RegisterRingAggregator[int](ctx, 3, opts...)
+			},
+			want: []int{3, 4, 5},
+		},
+		"set": {
+			register: func(ctx context.Context, opts ...aggregator.Option) context.Context {
+				return aggregator.RegisterSetAggregator(ctx, func(i int) int { return i % 2 }, opts...)
+			},
+			want: []int{1, 2},
+		},
+		"topk": {
+			register: func(ctx context.Context, opts ...aggregator.Option) context.Context {
+				return aggregator.RegisterTopKAggregator(ctx, 2, cmp.Compare[int], opts...)
+			},
+			want: []int{5, 4},
+		},
+		"reservoir": {
+			register: func(ctx context.Context, opts ...aggregator.Option) context.Context {
+				return aggregator.RegisterReservoirAggregator[int](ctx, 10, opts...)
+			},
+			want: []int{1, 2, 3, 4, 5},
+		},
+	}
+
+	for kind, tt := range special {
+		for name, opts := range concurrencyFlavors() {
+			t.Run(kind+"/"+name, func(t *testing.T) {
+				var late []int
+				opts = append(opts, aggregator.WithLateCollectHook(func(item int) { late = append(late, item) }))
+				ctx := tt.register(context.Background(), opts...)
+
+				assert.NoError(t, aggregator.CollectMany(ctx, []int{1, 2, 3, 4, 5}))
+				results, _ := aggregator.Aggregate[int](ctx)
+				if kind == "reservoir" {
+					assert.ElementsMatch(t, tt.want, results)
+				} else {
+					assert.Equal(t, tt.want, results)
+				}
+
+				assert.NoError(t, aggregator.Seal[int](ctx))
+				assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.CollectMany(ctx, []int{6, 7}))
+				assert.Equal(t, []int{6, 7}, late)
+
+				n, _ := aggregator.LateCollects[int](ctx)
+				assert.Equal(t, 2, n)
+			})
+		}
+	}
+}
+
+func TestCollectMany_ShardedKeepsCollectOrder(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithShards(8))
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, aggregator.CollectMany(ctx, []int{3 * i, 3*i + 1, 3*i + 2}))
+	}
+
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, intRange(300), results)
+}
+
+func TestCollectMany_Concurrent(t *testing.T) {
+	flavors := map[string][]aggregator.Option{
+		"concurrent": {aggregator.WithConcurrency()},
+		"sharded":    {aggregator.WithShards(4)},
+	}
+
+	for name, opts := range flavors {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			for i := 0; i < 50; i++ {
+				ctx, done := aggregator.WaitFuncOf[int](ctx)
+				go func(i int) {
+					defer done()
+					_ = aggregator.CollectMany(ctx, []int{2 * i, 2*i + 1})
+				}(i)
+			}
+
+			results, err := aggregator.Aggregate[int](ctx)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, intRange(100), results)
+		})
+	}
+}
+
+func TestCollectMany_Handle(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithConcurrency())
+
+	handle, err := aggregator.Lookup[int](ctx)
+	assert.NoError(t, err)
+
+	assert.NoError(t, handle.CollectMany([]int{1, 2, 3}))
+	assert.Equal(t, []int{1, 2, 3}, handle.Aggregate())
+}
+
+// oneByOne hides every method but those of SealableAggregator, so it collects
+// one item at a time
+type oneByOne struct {
+	aggregator.SealableAggregator[int]
+}
+
+func TestCollectMany_FallsBackToCollect(t *testing.T) {
+	ring := aggregator.NewRing[int](3).(aggregator.SealableAggregator[int])
+	ctx := aggregator.RegisterAggregator[int](context.Background(), oneByOne{ring})
+
+	assert.NoError(t, aggregator.CollectMany(ctx, []int{1, 2, 3, 4}))
+
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, []int{2, 3, 4}, results)
+
+	assert.NoError(t, aggregator.Seal[int](ctx))
+	assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.CollectMany(ctx, []int{5, 6}))
+
+	// The first error stops the batch
+	n, _ := aggregator.LateCollects[int](ctx)
+	assert.Equal(t, 1, n)
+}
+
+func BenchmarkCollectMany(b *testing.B) {
+	items := intRange(64)
+
+	b.Run("Collect", func(b *testing.B) {
+		ctx := aggregator.Register[int](context.Background(), aggregator.WithConcurrency())
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, item := range items {
+				_ = aggregator.Collect(ctx, item)
+			}
+			_, _ = aggregator.Drain[int](ctx)
+		}
+	})
+
+	b.Run("CollectMany", func(b *testing.B) {
+		ctx := aggregator.Register[int](context.Background(), aggregator.WithConcurrency())
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = aggregator.CollectMany(ctx, items)
+			_, _ = aggregator.Drain[int](ctx)
+		}
+	})
+}
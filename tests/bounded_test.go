@@ -0,0 +1,219 @@
+package aggregator_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestBounded_DropNewest(t *testing.T) {
+	for name, opts := range lifecycleFlavors() {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, aggregator.WithMaxItems(3, aggregator.OverflowDropNewest))
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			for i := 0; i < 5; i++ {
+				assert.NoError(t, aggregator.Collect(ctx, i))
+			}
+
+			results, dropped, err := aggregator.AggregateWithDropped[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{0, 1, 2}, results)
+			assert.Equal(t, 2, dropped)
+		})
+	}
+}
+
+func TestBounded_DropOldest(t *testing.T) {
+	for name, opts := range lifecycleFlavors() {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, aggregator.WithMaxItems(3, aggregator.OverflowDropOldest))
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			for i := 0; i < 10; i++ {
+				assert.NoError(t, aggregator.Collect(ctx, i))
+			}
+
+			results, dropped, err := aggregator.AggregateWithDropped[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{7, 8, 9}, results)
+			assert.Equal(t, 7, dropped)
+		})
+	}
+}
+
+func TestBounded_Error(t *testing.T) {
+	for name, opts := range lifecycleFlavors() {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, aggregator.WithMaxItems(2, aggregator.OverflowError))
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			assert.NoError(t, aggregator.Collect(ctx, 1))
+			assert.NoError(t, aggregator.Collect(ctx, 2))
+			assert.Equal(t, aggregator.ErrCapacityExceeded, aggregator.Collect(ctx, 3))
+
+			results, dropped, err := aggregator.AggregateWithDropped[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{1, 2}, results)
+			assert.Equal(t, 1, dropped)
+		})
+	}
+}
+
+func TestBounded_StreamingCallbackOnlyForStoredItems(t *testing.T) {
+	var calls int32
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithCallback(func(int) { atomic.AddInt32(&calls, 1) }),
+		aggregator.WithMaxItems(2, aggregator.OverflowDropNewest),
+	)
+
+	for i := 0; i < 5; i++ {
+		_ = aggregator.Collect(ctx, i)
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestBounded_BlockUntilDrain(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithConcurrency(),
+		aggregator.WithMaxItems(1, aggregator.OverflowBlock),
+	)
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+
+	collected := make(chan error)
+	go func() {
+		collected <- aggregator.Collect(ctx, 2)
+	}()
+
+	select {
+	case <-collected:
+		t.Fatal("collect should block while the aggregator is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	drained, err := aggregator.Drain[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, drained)
+
+	assert.NoError(t, <-collected)
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, results)
+}
+
+func TestBounded_BlockRespectsCancellation(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithConcurrency(),
+		aggregator.WithMaxItems(1, aggregator.OverflowBlock),
+	)
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+
+	cancelCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	err := aggregator.Collect(cancelCtx, 2)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, dropped, _ := aggregator.AggregateWithDropped[int](ctx)
+	assert.Equal(t, 1, dropped)
+}
+
+func TestBounded_BlockWakesOnSeal(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithConcurrency(),
+		aggregator.WithMaxItems(1, aggregator.OverflowBlock),
+	)
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+
+	collected := make(chan error)
+	go func() {
+		collected <- aggregator.Collect(ctx, 2)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, aggregator.Seal[int](ctx))
+	assert.Equal(t, aggregator.ErrAggregatorClosed, <-collected)
+}
+
+func TestBounded_BlockedWaiterDoesNotDeadlockAggregate(t *testing.T) {
+	for name, opts := range map[string][]aggregator.Option{
+		"concurrent": {aggregator.WithConcurrency()},
+		"streaming":  {aggregator.WithConcurrency(), aggregator.WithCallback(func(int) {})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.Register[int](context.Background(),
+				append(opts, aggregator.WithMaxItems(1, aggregator.OverflowBlock))...,
+			)
+			assert.NoError(t, aggregator.Collect(ctx, 1))
+
+			ctx, done := aggregator.WaitFuncOf[int](ctx)
+			collected := make(chan error, 1)
+			go func() {
+				defer done()
+				collected <- aggregator.Collect(ctx, 2)
+			}()
+
+			results, dropped, err := aggregator.AggregateWithDropped[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{1}, results)
+			assert.Equal(t, 1, dropped)
+			assert.Equal(t, aggregator.ErrCapacityExceeded, <-collected)
+		})
+	}
+}
+
+func TestBounded_BlockOnSequentialAggregator(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithMaxItems(1, aggregator.OverflowBlock),
+	)
+
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+	assert.Equal(t, aggregator.ErrCapacityExceeded, aggregator.Collect(ctx, 2))
+}
+
+func TestBounded_HandleUsesLookupContext(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithConcurrency(),
+		aggregator.WithMaxItems(1, aggregator.OverflowBlock),
+	)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	handle, err := aggregator.Lookup[int](cancelCtx)
+	assert.NoError(t, err)
+	assert.NoError(t, handle.Collect(1))
+
+	cancel()
+	assert.ErrorIs(t, handle.Collect(2), context.Canceled)
+}
+
+func TestBounded_Inspect(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithMaxItems(2, aggregator.OverflowDropNewest),
+	)
+	for i := 0; i < 5; i++ {
+		_ = aggregator.Collect(ctx, i)
+	}
+
+	infos := aggregator.Inspect(ctx)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, 2, infos[0].MaxItems)
+	assert.Equal(t, 3, infos[0].Dropped)
+}
+
+func TestBounded_CustomAggregator(t *testing.T) {
+	ctx := aggregator.RegisterAggregator[string](context.Background(), &upperAggregator{})
+	_ = aggregator.Collect(ctx, "a")
+
+	results, dropped, err := aggregator.AggregateWithDropped[string](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A"}, results)
+	assert.Zero(t, dropped)
+
+	_, err = aggregator.Drain[string](ctx)
+	assert.Equal(t, aggregator.ErrNotBounded, err)
+}
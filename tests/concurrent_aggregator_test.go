@@ -15,8 +15,9 @@ func TestConcurrentContextAggregator_CollectNotFoundAggregator(t *testing.T) {
 }
 
 func TestConcurrentContextAggregator_CollectInvalidType(t *testing.T) {
-	ctx := aggregator.RegisterConcurrentContextAggregator[int](context.Background())
-	err := funcBaseCollecInt32(ctx)
+	key := "test"
+	ctx := aggregator.RegisterConcurrentContextAggregator[int](context.Background(), key)
+	err := funcBaseCollecInt32(ctx, key)
 	assert.Equal(t, err, aggregator.ErrInvalidType)
 }
 
@@ -27,11 +28,12 @@ func TestConcurrentContextAggregator_AggregateNotFoundAggregator(t *testing.T) {
 }
 
 func TestConcurrentContextAggregator_AggregateInvalidType(t *testing.T) {
-	ctx := aggregator.RegisterConcurrentContextAggregator[int32](context.Background())
-	err := funcBaseCollecInt32(ctx)
+	key := "test"
+	ctx := aggregator.RegisterConcurrentContextAggregator[int32](context.Background(), key)
+	err := funcBaseCollecInt32(ctx, key)
 	assert.Nil(t, err)
 
-	result, err := aggregator.Aggregate[int](ctx)
+	result, err := aggregator.Aggregate[int](ctx, key)
 	assert.Nil(t, result)
 	assert.Equal(t, err, aggregator.ErrInvalidType)
 }
@@ -0,0 +1,129 @@
+package aggregator_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+// upperAggregator is a user-implemented aggregator storing upper-cased strings
+type upperAggregator struct {
+	datas []string
+}
+
+func (a *upperAggregator) Collect(data string) {
+	a.datas = append(a.datas, strings.ToUpper(data))
+}
+
+func (a *upperAggregator) Aggregate() []string {
+	return a.datas
+}
+
+func TestRegisterAggregator_Custom(t *testing.T) {
+	agg := &upperAggregator{}
+	ctx := aggregator.RegisterAggregator[string](context.Background(), agg)
+
+	assert.NoError(t, aggregator.Collect(ctx, "hello"))
+	assert.NoError(t, aggregator.Collect(ctx, "world"))
+
+	results, err := aggregator.Aggregate[string](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"HELLO", "WORLD"}, results)
+
+	filtered, err := aggregator.AggregateWithFilter(ctx, func(s string) bool {
+		return strings.HasPrefix(s, "W")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"WORLD"}, filtered)
+}
+
+func TestRegisterAggregator_WithKey(t *testing.T) {
+	ctx := context.Background()
+	ctx = aggregator.RegisterAggregator[string](ctx, &upperAggregator{}, "upper")
+	ctx = aggregator.RegisterAggregator(ctx, aggregator.NewBase[string](), "plain")
+
+	_ = aggregator.Collect(ctx, "a", "upper")
+	_ = aggregator.Collect(ctx, "a", "plain")
+
+	upper, err := aggregator.Aggregate[string](ctx, "upper")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A"}, upper)
+
+	plain, err := aggregator.Aggregate[string](ctx, "plain")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, plain)
+}
+
+func TestNewBase(t *testing.T) {
+	agg := aggregator.NewBase[int]()
+	agg.Collect(1)
+	agg.Collect(2)
+
+	assert.Equal(t, []int{1, 2}, agg.Aggregate())
+}
+
+func TestNewConcurrent(t *testing.T) {
+	agg := aggregator.NewConcurrent[int]()
+
+	for i := 0; i < 10; i++ {
+		agg.AddWait()
+		go func(val int) {
+			defer agg.Done()
+			agg.Collect(val)
+		}(i)
+	}
+
+	assert.Len(t, agg.Aggregate(), 10)
+}
+
+func TestNewStreaming(t *testing.T) {
+	var streamed []string
+	agg := aggregator.NewStreaming(func(s string) {
+		streamed = append(streamed, s)
+	})
+
+	agg.Collect("a")
+	agg.Collect("b")
+
+	assert.Equal(t, []string{"a", "b"}, streamed)
+	assert.Equal(t, []string{"a", "b"}, agg.Aggregate())
+}
+
+func TestNewConcurrentStreaming(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		streamed int
+	)
+	agg := aggregator.NewConcurrentStreaming(func(int) {
+		mu.Lock()
+		defer mu.Unlock()
+		streamed++
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(val int) {
+			defer wg.Done()
+			agg.Collect(val)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, streamed)
+	assert.Len(t, agg.Aggregate(), 10)
+}
+
+func TestNew_WithOptions(t *testing.T) {
+	agg := aggregator.New[int](aggregator.WithConcurrency(), aggregator.WithCapacity(10))
+	_, ok := agg.(aggregator.ConcurrentContextAggregator[int])
+	assert.True(t, ok)
+
+	ctx := aggregator.RegisterAggregator(context.Background(), agg)
+	_ = aggregator.Collect(ctx, 1)
+	assert.Equal(t, []int{1}, agg.Aggregate())
+}
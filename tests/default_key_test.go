@@ -0,0 +1,124 @@
+package aggregator_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestDefaultKey_DifferentTypesSideBySide(t *testing.T) {
+	ctx := context.Background()
+	ctx = aggregator.RegisterBaseContextAggregator[error](ctx)
+	ctx = aggregator.RegisterBaseContextAggregator[string](ctx)
+
+	errFoo := errors.New("foo")
+	assert.NoError(t, aggregator.Collect(ctx, errFoo))
+	assert.NoError(t, aggregator.Collect(ctx, "bar"))
+
+	errs, err := aggregator.Aggregate[error](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []error{errFoo}, errs)
+
+	strs, err := aggregator.Aggregate[string](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar"}, strs)
+}
+
+func TestDefaultKey_UnregisteredTypeNotFound(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregator[int](context.Background())
+
+	err := aggregator.Collect(ctx, int32(1))
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+
+	result, err := aggregator.Aggregate[int32](ctx)
+	assert.Nil(t, result)
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+}
+
+func TestDefaultKey_SameTypeShadows(t *testing.T) {
+	outer := aggregator.RegisterBaseContextAggregator[int](context.Background())
+	inner := aggregator.RegisterBaseContextAggregator[int](outer)
+
+	_ = aggregator.Collect(outer, 1)
+	_ = aggregator.Collect(inner, 2)
+
+	outerResult, _ := aggregator.Aggregate[int](outer)
+	innerResult, _ := aggregator.Aggregate[int](inner)
+	assert.Equal(t, []int{1}, outerResult)
+	assert.Equal(t, []int{2}, innerResult)
+}
+
+func TestDefaultKey_WaitFuncOf(t *testing.T) {
+	ctx := context.Background()
+	ctx = aggregator.RegisterConcurrentContextAggregator[int](ctx)
+	ctx = aggregator.RegisterConcurrentContextAggregator[string](ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		ctx, done := aggregator.WaitFuncOf[int](ctx)
+		wg.Add(1)
+		go func(val int) {
+			defer wg.Done()
+			defer done()
+			_ = aggregator.Collect(ctx, val)
+		}(i)
+	}
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4}, results)
+	wg.Wait()
+}
+
+func TestDefaultKey_WaitFuncTargetsLatestKeyless(t *testing.T) {
+	ctx := aggregator.RegisterConcurrentContextAggregator[int](context.Background())
+
+	ctx, done := aggregator.WaitFunc(ctx)
+	go func() {
+		defer done()
+		_ = aggregator.Collect(ctx, 1)
+	}()
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, results)
+}
+
+func TestDefaultKey_WaitFuncPicksTheWaitableKeyless(t *testing.T) {
+	ctx := aggregator.RegisterConcurrentContextAggregator[error](context.Background())
+	ctx = aggregator.RegisterBaseContextAggregator[string](ctx)
+
+	ctx, done := aggregator.WaitFunc(ctx)
+	go func() {
+		defer done()
+		_ = aggregator.Collect(ctx, errors.New("failed"))
+	}()
+
+	errs, err := aggregator.Aggregate[error](ctx)
+	assert.NoError(t, err)
+	assert.Len(t, errs, 1)
+
+	// A non-concurrent aggregator of the same type shadows the waitable one
+	ctx = aggregator.RegisterBaseContextAggregator[error](ctx)
+	assert.NotPanics(t, func() {
+		_, done := aggregator.WaitFunc(ctx)
+		done()
+	})
+}
+
+func TestDefaultKey_WaitFuncPanicsWhenAmbiguous(t *testing.T) {
+	ctx := aggregator.RegisterConcurrentContextAggregator[int](context.Background())
+	ctx = aggregator.RegisterConcurrentContextAggregator[string](ctx)
+
+	assert.Panics(t, func() { aggregator.WaitFunc(ctx) })
+	assert.NotPanics(t, func() { aggregator.WaitFunc(ctx, "test") })
+
+	// Registering the same type again shadows it rather than adding a type
+	ctx = aggregator.RegisterConcurrentContextAggregator[int](context.Background())
+	ctx = aggregator.RegisterConcurrentContextAggregator[int](ctx)
+	assert.NotPanics(t, func() { aggregator.WaitFunc(ctx) })
+}
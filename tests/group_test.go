@@ -0,0 +1,157 @@
+package aggregator_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+type logLine struct {
+	Severity string
+	Message  string
+}
+
+func severity(l logLine) string {
+	return l.Severity
+}
+
+func collectLogLines(ctx context.Context) {
+	for _, line := range []logLine{
+		{"error", "db down"},
+		{"info", "started"},
+		{"error", "retry failed"},
+		{"warn", "slow query"},
+	} {
+		_ = aggregator.Collect(ctx, line)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	ctx := aggregator.Register[logLine](context.Background())
+	collectLogLines(ctx)
+
+	groups, err := aggregator.AggregateGroupBy(ctx, severity)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]logLine{
+		"error": {{"error", "db down"}, {"error", "retry failed"}},
+		"info":  {{"info", "started"}},
+		"warn":  {{"warn", "slow query"}},
+	}, groups)
+}
+
+func TestGroupByWithTransform(t *testing.T) {
+	ctx := aggregator.Register[logLine](context.Background())
+	collectLogLines(ctx)
+
+	messages, err := aggregator.AggregateGroupByWithTransform(ctx, severity, func(l logLine) string {
+		return l.Message
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db down", "retry failed"}, messages["error"])
+}
+
+func TestCountBy(t *testing.T) {
+	ctx := aggregator.Register[logLine](context.Background())
+	collectLogLines(ctx)
+
+	counts, err := aggregator.AggregateCountBy(ctx, severity)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"error": 2, "info": 1, "warn": 1}, counts)
+}
+
+func TestGroupBy_NotFound(t *testing.T) {
+	_, err := aggregator.AggregateGroupBy(context.Background(), severity)
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+}
+
+func TestGroupAggregator(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.RegisterGroupAggregator(context.Background(), severity, opts...)
+			collectLogLines(ctx)
+
+			groups, err := aggregator.AggregateGrouped[logLine, string](ctx)
+			assert.NoError(t, err)
+			assert.Len(t, groups, 3)
+			assert.Equal(t, []logLine{{"error", "db down"}, {"error", "retry failed"}}, groups["error"])
+
+			counts, err := aggregator.AggregateGroupCounts[logLine, string](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]int{"error": 2, "info": 1, "warn": 1}, counts)
+
+			// Group by group, in the order each group was first collected
+			all, err := aggregator.Aggregate[logLine](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []logLine{
+				{"error", "db down"},
+				{"error", "retry failed"},
+				{"info", "started"},
+				{"warn", "slow query"},
+			}, all)
+		})
+	}
+}
+
+func TestGroupAggregator_KeyFnPanicReleasesLock(t *testing.T) {
+	agg := aggregator.NewGroup(func(i int) int { return 10 / i }, aggregator.WithConcurrency())
+
+	assert.Panics(t, func() { agg.Collect(0) })
+	agg.Collect(5)
+	assert.Equal(t, []int{5}, agg.Aggregate())
+}
+
+func TestGroupAggregator_SnapshotIsolation(t *testing.T) {
+	ctx := aggregator.RegisterGroupAggregator(context.Background(), severity)
+	collectLogLines(ctx)
+
+	groups, _ := aggregator.AggregateGrouped[logLine, string](ctx)
+	groups["error"][0].Message = "changed"
+
+	again, _ := aggregator.AggregateGrouped[logLine, string](ctx)
+	assert.Equal(t, "db down", again["error"][0].Message)
+}
+
+func TestGroupAggregator_Concurrent(t *testing.T) {
+	ctx := aggregator.RegisterGroupAggregator(context.Background(), func(i int) bool { return i%2 == 0 },
+		aggregator.WithConcurrency(),
+	)
+
+	for i := 0; i < 100; i++ {
+		ctx, done := aggregator.WaitFuncOf[int](ctx)
+		go func(i int) {
+			defer done()
+			_ = aggregator.Collect(ctx, i)
+		}(i)
+	}
+
+	counts, err := aggregator.AggregateGroupCounts[int, bool](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[bool]int{true: 50, false: 50}, counts)
+}
+
+func TestGroupAggregator_NotGrouped(t *testing.T) {
+	ctx := aggregator.Register[logLine](context.Background())
+	_, err := aggregator.AggregateGrouped[logLine, string](ctx)
+	assert.Equal(t, aggregator.ErrNotGrouped, err)
+
+	ctx = aggregator.RegisterGroupAggregator(context.Background(), severity)
+	_, err = aggregator.AggregateGrouped[logLine, int](ctx)
+	assert.Equal(t, aggregator.ErrNotGrouped, err)
+}
+
+func TestGroupAggregator_DrainAndSeal(t *testing.T) {
+	ctx := aggregator.RegisterGroupAggregator(context.Background(), severity)
+	collectLogLines(ctx)
+
+	drained, err := aggregator.Drain[logLine](ctx)
+	assert.NoError(t, err)
+	assert.Len(t, drained, 4)
+
+	groups, _ := aggregator.AggregateGrouped[logLine, string](ctx)
+	assert.Empty(t, groups)
+
+	assert.NoError(t, aggregator.Seal[logLine](ctx))
+	assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, logLine{}))
+}
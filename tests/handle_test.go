@@ -0,0 +1,114 @@
+package aggregator_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestLookup_NotFoundAndInvalidType(t *testing.T) {
+	_, err := aggregator.Lookup[int](context.Background())
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+
+	ctx := aggregator.RegisterBaseContextAggregator[int](context.Background(), "test")
+	_, err = aggregator.Lookup[string](ctx, "test")
+	assert.Equal(t, aggregator.ErrInvalidType, err)
+}
+
+func TestLookup_SharesAggregatorWithContext(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregator[int](context.Background())
+
+	handle, err := aggregator.Lookup[int](ctx)
+	assert.NoError(t, err)
+
+	assert.NoError(t, handle.Collect(1))
+	assert.NoError(t, aggregator.Collect(ctx, 2))
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, results)
+	assert.Equal(t, []int{1, 2}, handle.Aggregate())
+}
+
+func TestLookup_ConcurrentStreaming(t *testing.T) {
+	var callbackCount int32
+	ctx := aggregator.RegisterConcurrentStreamingAggregator(context.Background(), func(int) {
+		atomic.AddInt32(&callbackCount, 1)
+	}, "stream")
+
+	handle, err := aggregator.Lookup[int](ctx, "stream")
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(val int) {
+			defer wg.Done()
+			_ = handle.Collect(val)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(10), atomic.LoadInt32(&callbackCount))
+	assert.Len(t, handle.Aggregate(), 10)
+}
+
+func TestLookupKey(t *testing.T) {
+	key := aggregator.NewKey[string]("messages")
+	ctx := aggregator.RegisterKey(context.Background(), key)
+
+	handle, err := aggregator.LookupKey(ctx, key)
+	assert.NoError(t, err)
+	assert.NoError(t, handle.Collect("hello"))
+
+	results, err := aggregator.AggregateKey(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, results)
+
+	_, err = aggregator.LookupKey(context.Background(), key)
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+}
+
+func TestHandle_CollectDoesNotAllocate(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregatorWithCapacity[int](context.Background(), 1000, "test")
+	handle, err := aggregator.Lookup[int](ctx, "test")
+	assert.NoError(t, err)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = handle.Collect(1)
+	})
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkCollect_Context(b *testing.B) {
+	ctx := aggregator.RegisterBaseContextAggregatorWithCapacity[int](context.Background(), b.N, "test")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = aggregator.Collect(ctx, i, "test")
+	}
+}
+
+func BenchmarkCollect_Handle(b *testing.B) {
+	ctx := aggregator.RegisterBaseContextAggregatorWithCapacity[int](context.Background(), b.N, "test")
+	handle, _ := aggregator.Lookup[int](ctx, "test")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = handle.Collect(i)
+	}
+}
+
+func BenchmarkCollect_HandleConcurrent(b *testing.B) {
+	ctx := aggregator.RegisterConcurrentContextAggregatorWithCapacity[int](context.Background(), b.N, "test")
+	handle, _ := aggregator.Lookup[int](ctx, "test")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = handle.Collect(i)
+	}
+}
@@ -0,0 +1,64 @@
+package aggregator_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestInspect_Empty(t *testing.T) {
+	assert.Empty(t, aggregator.Inspect(context.Background()))
+}
+
+func TestInspect_ListsAggregators(t *testing.T) {
+	key := aggregator.NewKey[float64]("latencies")
+
+	ctx := context.Background()
+	ctx = aggregator.RegisterBaseContextAggregatorWithCapacity[error](ctx, 4)
+	ctx = aggregator.RegisterConcurrentContextAggregator[int](ctx, "numbers")
+	ctx = aggregator.RegisterStreamingAggregator(ctx, func(string) {}, "stream")
+	ctx = aggregator.RegisterConcurrentStreamingAggregator(ctx, func(string) {}, "stream", "concurrent")
+	ctx = aggregator.RegisterKey(ctx, key)
+	ctx = aggregator.RegisterAggregator[string](ctx, &upperAggregator{}, "upper")
+
+	_ = aggregator.Collect(ctx, int(1), "numbers")
+	_ = aggregator.Collect(ctx, int(2), "numbers")
+	ctx, done := aggregator.WaitFunc(ctx, "numbers")
+
+	infos := aggregator.Inspect(ctx)
+	assert.Equal(t, []aggregator.AggregatorInfo{
+		{Key: "", ElemType: "error", Kind: aggregator.KindBase, Len: 0, Cap: 4},
+		{Key: "latencies", ElemType: "float64", Kind: aggregator.KindBase, Len: 0, Cap: 0},
+		{Key: "numbers", ElemType: "int", Kind: aggregator.KindConcurrent, Len: 2, Cap: 2, Waiters: 1},
+		{Key: "stream", ElemType: "string", Kind: aggregator.KindStreaming, Len: 0, Cap: 0},
+		{Key: "stream_concurrent", ElemType: "string", Kind: aggregator.KindConcurrentStreaming, Len: 0, Cap: 0},
+		{Key: "upper", ElemType: "string", Kind: aggregator.KindCustom, Len: -1, Cap: -1},
+	}, infos)
+
+	done()
+	infos = aggregator.Inspect(ctx)
+	assert.Equal(t, 0, infos[2].Waiters)
+}
+
+func TestInspect_String(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregator[string](context.Background(), "errors")
+	_ = aggregator.Collect(ctx, "boom", "errors")
+
+	infos := aggregator.Inspect(ctx)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, `key="errors" type=string kind=base len=1 cap=1 waiters=0`, infos[0].String())
+}
+
+func TestDescribe(t *testing.T) {
+	ctx := context.Background()
+	ctx = aggregator.RegisterBaseContextAggregator[error](ctx)
+	ctx = aggregator.RegisterConcurrentContextAggregator[int](ctx, "numbers")
+
+	expected := "" +
+		"KEY        TYPE   KIND        LEN  CAP  WAITERS\n" +
+		"<default>  error  base        0    0    0\n" +
+		"numbers    int    concurrent  0    0    0\n"
+	assert.Equal(t, expected, aggregator.Describe(ctx))
+}
@@ -0,0 +1,181 @@
+package aggregator_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestAll(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.Register[int](context.Background(), opts...)
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{1, 2, 3}))
+
+			assert.Equal(t, []int{1, 2, 3}, slices.Collect(aggregator.All[int](ctx)))
+		})
+	}
+}
+
+func TestAll_SnapshotsWhenIterationStarts(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background())
+	seq := aggregator.All[int](ctx)
+
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+	assert.Equal(t, []int{1}, slices.Collect(seq))
+
+	for item := range seq {
+		// Collecting while iterating does not change the items being iterated
+		assert.NoError(t, aggregator.Collect(ctx, item+1))
+	}
+	assert.Equal(t, []int{1, 2}, slices.Collect(seq))
+}
+
+func TestAll_OverwritingDuringIteration(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, aggregator.WithMaxItems(3, aggregator.OverflowDropOldest))
+			ctx := aggregator.Register[int](context.Background(), opts...)
+			assert.NoError(t, aggregator.CollectMany(ctx, []int{1, 2, 3}))
+
+			var seen []int
+			for item := range aggregator.All[int](ctx) {
+				seen = append(seen, item)
+				// Evicts the oldest stored items, not the ones being iterated
+				assert.NoError(t, aggregator.Collect(ctx, item*10))
+			}
+			assert.Equal(t, []int{1, 2, 3}, seen)
+
+			drained, _ := aggregator.Drain[int](ctx)
+			assert.Equal(t, []int{10, 20, 30}, drained)
+			assert.Empty(t, slices.Collect(aggregator.All[int](ctx)))
+		})
+	}
+}
+
+func TestAll_NotFound(t *testing.T) {
+	assert.Empty(t, slices.Collect(aggregator.All[int](context.Background())))
+
+	ctx := aggregator.Register[string](context.Background(), aggregator.WithKey("names"))
+	assert.Empty(t, slices.Collect(aggregator.All[int](ctx, "names")))
+}
+
+func TestAll_KeyAndHandle(t *testing.T) {
+	key := aggregator.NewKey[string]("names")
+	ctx := aggregator.RegisterKey(context.Background(), key)
+	assert.NoError(t, aggregator.CollectKey(ctx, key, "a"))
+
+	assert.Equal(t, []string{"a"}, slices.Collect(aggregator.AllKey(ctx, key)))
+
+	handle, err := aggregator.LookupKey(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, slices.Collect(handle.All()))
+}
+
+func TestAll_Pipeline(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background())
+	assert.NoError(t, aggregator.CollectMany(ctx, []int{5, 1, 2, 2, 3, 4, 4, 6, 8, 9}))
+
+	even := aggregator.Filter(aggregator.All[int](ctx), func(n int) bool { return n%2 == 0 })
+	squares := aggregator.Map(aggregator.Distinct(even), func(n int) int { return n * n })
+	page := aggregator.Take(aggregator.Skip(squares, 1), 2)
+
+	assert.Equal(t, []int{16, 36}, slices.Collect(page))
+}
+
+func TestFilterAndMap(t *testing.T) {
+	seq := slices.Values([]int{1, 2, 3, 4})
+
+	assert.Equal(t, []int{2, 4}, slices.Collect(aggregator.Filter(seq, func(n int) bool { return n%2 == 0 })))
+	assert.Equal(t, []string{"1", "2", "3", "4"}, slices.Collect(aggregator.Map(seq, func(n int) string {
+		return string(rune('0' + n))
+	})))
+}
+
+func TestTakeAndSkip(t *testing.T) {
+	seq := slices.Values([]int{1, 2, 3, 4})
+
+	assert.Equal(t, []int{1, 2}, slices.Collect(aggregator.Take(seq, 2)))
+	assert.Equal(t, []int{1, 2, 3, 4}, slices.Collect(aggregator.Take(seq, 10)))
+	assert.Empty(t, slices.Collect(aggregator.Take(seq, 0)))
+
+	assert.Equal(t, []int{3, 4}, slices.Collect(aggregator.Skip(seq, 2)))
+	assert.Equal(t, []int{1, 2, 3, 4}, slices.Collect(aggregator.Skip(seq, -1)))
+	assert.Empty(t, slices.Collect(aggregator.Skip(seq, 10)))
+}
+
+func TestTake_StopsPulling(t *testing.T) {
+	pulled := 0
+	seq := func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			pulled++
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	assert.Equal(t, []int{0, 1, 2}, slices.Collect(aggregator.Take(seq, 3)))
+	assert.Equal(t, 3, pulled)
+}
+
+func TestDistinct(t *testing.T) {
+	seq := slices.Values([]string{"a", "b", "a", "c", "b"})
+	assert.Equal(t, []string{"a", "b", "c"}, slices.Collect(aggregator.Distinct(seq)))
+}
+
+func TestChunk(t *testing.T) {
+	seq := slices.Values([]int{1, 2, 3, 4, 5})
+
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, slices.Collect(aggregator.Chunk(seq, 2)))
+	assert.Equal(t, [][]int{{1, 2, 3, 4, 5}}, slices.Collect(aggregator.Chunk(seq, 5)))
+	assert.Empty(t, slices.Collect(aggregator.Chunk(slices.Values([]int{}), 2)))
+
+	// Chunks are not reused, so they can be kept
+	chunks := slices.Collect(aggregator.Chunk(seq, 2))
+	chunks[0][0] = 100
+	assert.Equal(t, []int{3, 4}, chunks[1])
+
+	assert.Panics(t, func() { aggregator.Chunk(seq, 0) })
+}
+
+func TestChunk_StopsEarly(t *testing.T) {
+	seq := slices.Values([]int{1, 2, 3, 4, 5})
+
+	var first []int
+	for chunk := range aggregator.Chunk(seq, 2) {
+		first = chunk
+		break
+	}
+	assert.Equal(t, []int{1, 2}, first)
+}
+
+func BenchmarkAll_Pipeline(b *testing.B) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithCapacity(10000))
+	assert.NoError(b, aggregator.CollectMany(ctx, intRange(10000)))
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sum := 0
+		even := aggregator.Filter(aggregator.All[int](ctx), func(n int) bool { return n%2 == 0 })
+		for n := range aggregator.Map(even, func(n int) int { return n * 2 }) {
+			sum += n
+		}
+	}
+}
+
+func BenchmarkAggregateWithFilterAndTransform(b *testing.B) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithCapacity(10000))
+	assert.NoError(b, aggregator.CollectMany(ctx, intRange(10000)))
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = aggregator.AggregateWithFilterAndTransform(ctx,
+			func(n int) bool { return n%2 == 0 },
+			func(n int) int { return n * 2 },
+		)
+	}
+}
@@ -0,0 +1,109 @@
+package aggregator_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestKey_CollectAndAggregate(t *testing.T) {
+	key := aggregator.NewKey[string]("messages")
+	ctx := aggregator.RegisterKey(context.Background(), key)
+
+	assert.NoError(t, aggregator.CollectKey(ctx, key, "hello"))
+	assert.NoError(t, aggregator.CollectKey(ctx, key, "world"))
+
+	results, err := aggregator.AggregateKey(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hello", "world"}, results)
+	assert.Equal(t, "messages", key.String())
+}
+
+func TestKey_NotFound(t *testing.T) {
+	key := aggregator.NewKey[int]("numbers")
+
+	err := aggregator.CollectKey(context.Background(), key, 1)
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+
+	results, err := aggregator.AggregateKey(context.Background(), key)
+	assert.Nil(t, results)
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+}
+
+func TestKey_SameNameDoesNotCollide(t *testing.T) {
+	key1 := aggregator.NewKey[int]("numbers")
+	key2 := aggregator.NewKey[int]("numbers")
+
+	ctx := context.Background()
+	ctx = aggregator.RegisterKey(ctx, key1)
+	ctx = aggregator.RegisterKey(ctx, key2)
+
+	_ = aggregator.CollectKey(ctx, key1, 1)
+	_ = aggregator.CollectKey(ctx, key2, 2)
+
+	results1, err := aggregator.AggregateKey(ctx, key1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, results1)
+
+	results2, err := aggregator.AggregateKey(ctx, key2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, results2)
+}
+
+func TestKey_StringKeysDoNotCollide(t *testing.T) {
+	key := aggregator.NewKey[string]("a_b")
+
+	ctx := context.Background()
+	ctx = aggregator.RegisterBaseContextAggregator[string](ctx, "a", "b")
+	ctx = aggregator.RegisterKey(ctx, key)
+
+	_ = aggregator.Collect(ctx, "string keys", "a", "b")
+	_ = aggregator.CollectKey(ctx, key, "typed key")
+
+	results, err := aggregator.AggregateKey(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"typed key"}, results)
+}
+
+func TestKey_WithOptions(t *testing.T) {
+	var streamed []int
+	key := aggregator.NewKey[int]("numbers")
+	ctx := aggregator.RegisterKey(context.Background(), key,
+		aggregator.WithConcurrency(),
+		aggregator.WithCallback(func(n int) {
+			streamed = append(streamed, n)
+		}),
+	)
+
+	for i := 0; i < 5; i++ {
+		_ = aggregator.CollectKey(ctx, key, i)
+	}
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, streamed)
+
+	evens, err := aggregator.AggregateWithFilterKey(ctx, key, func(n int) bool {
+		return n%2 == 0
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 2, 4}, evens)
+
+	strs, err := aggregator.AggregateWithTransformKey(ctx, key, strconv.Itoa)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, strs)
+
+	odds, err := aggregator.AggregateWithFilterAndTransformKey(ctx, key,
+		func(n int) bool { return n%2 == 1 },
+		strconv.Itoa,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "3"}, odds)
+}
+
+func TestKey_NilKeyPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		aggregator.RegisterKey[int](context.Background(), nil)
+	})
+}
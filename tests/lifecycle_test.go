@@ -0,0 +1,151 @@
+package aggregator_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func lifecycleFlavors() map[string][]aggregator.Option {
+	return map[string][]aggregator.Option{
+		"base":                 nil,
+		"concurrent":           {aggregator.WithConcurrency()},
+		"streaming":            {aggregator.WithCallback(func(int) {})},
+		"concurrent streaming": {aggregator.WithConcurrency(), aggregator.WithCallback(func(int) {})},
+	}
+}
+
+func TestLifecycle_SealRejectsLateCollect(t *testing.T) {
+	for name, opts := range lifecycleFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.Register[int](context.Background(), opts...)
+			assert.NoError(t, aggregator.Collect(ctx, 1))
+
+			assert.NoError(t, aggregator.Seal[int](ctx))
+
+			assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, 2))
+			assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, 3))
+
+			results, err := aggregator.Aggregate[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{1}, results)
+
+			late, err := aggregator.LateCollects[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, late)
+		})
+	}
+}
+
+func TestLifecycle_AggregateAndSeal(t *testing.T) {
+	for name, opts := range lifecycleFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.Register[int](context.Background(), opts...)
+			_ = aggregator.Collect(ctx, 1)
+			_ = aggregator.Collect(ctx, 2)
+
+			results, err := aggregator.AggregateAndSeal[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{1, 2}, results)
+
+			assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, 3))
+		})
+	}
+}
+
+func TestLifecycle_AggregateAndSealWaitsForWaiters(t *testing.T) {
+	ctx := aggregator.RegisterConcurrentContextAggregator[int](context.Background(), "test")
+
+	var errs [5]error
+	for i := 0; i < 5; i++ {
+		ctx, done := aggregator.WaitFunc(ctx, "test")
+		go func(val int) {
+			defer done()
+			errs[val] = aggregator.Collect(ctx, val, "test")
+		}(i)
+	}
+
+	results, err := aggregator.AggregateAndSeal[int](ctx, "test")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4}, results)
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestLifecycle_LateCollectHook(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		late  []string
+		calls int32
+	)
+	ctx := aggregator.Register[string](context.Background(),
+		aggregator.WithConcurrency(),
+		aggregator.WithCallback(func(string) {
+			atomic.AddInt32(&calls, 1)
+		}),
+		aggregator.WithLateCollectHook(func(s string) {
+			mu.Lock()
+			defer mu.Unlock()
+			late = append(late, s)
+		}),
+	)
+
+	_ = aggregator.Collect(ctx, "on time")
+	_, _ = aggregator.AggregateAndSeal[string](ctx)
+	_ = aggregator.Collect(ctx, "straggler")
+
+	assert.Equal(t, []string{"straggler"}, late)
+	// Late items never reach the streaming callback
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestLifecycle_HandleAndKey(t *testing.T) {
+	key := aggregator.NewKey[int]("numbers")
+	ctx := aggregator.RegisterKey(context.Background(), key)
+	handle, err := aggregator.LookupKey(ctx, key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, handle.Collect(1))
+	assert.NoError(t, aggregator.CollectKey(ctx, key, 2))
+
+	results := handle.Aggregate()
+	assert.Equal(t, []int{1, 2}, results)
+
+	agg, ok := aggregator.New[int]().(aggregator.SealableAggregator[int])
+	assert.True(t, ok)
+	agg.Seal()
+	assert.Equal(t, aggregator.ErrAggregatorClosed, agg.TryCollect(1))
+
+	ctx = aggregator.RegisterAggregator[int](ctx, agg)
+	handle, err = aggregator.Lookup[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, aggregator.ErrAggregatorClosed, handle.Collect(2))
+	assert.Equal(t, 2, agg.LateCollects())
+}
+
+func TestLifecycle_AssignableCollectReportsClosed(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregator[error](context.Background())
+	assert.NoError(t, aggregator.Seal[error](ctx))
+
+	err := aggregator.Collect(ctx, &validationError{field: "email"})
+	assert.Equal(t, aggregator.ErrAggregatorClosed, err)
+}
+
+func TestLifecycle_NotSealable(t *testing.T) {
+	ctx := aggregator.RegisterAggregator[string](context.Background(), &upperAggregator{})
+
+	assert.Equal(t, aggregator.ErrNotSealable, aggregator.Seal[string](ctx))
+
+	_, err := aggregator.AggregateAndSeal[string](ctx)
+	assert.Equal(t, aggregator.ErrNotSealable, err)
+
+	_, err = aggregator.LateCollects[string](ctx)
+	assert.Equal(t, aggregator.ErrNotSealable, err)
+
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, aggregator.Seal[int](ctx))
+}
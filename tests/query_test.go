@@ -0,0 +1,240 @@
+package aggregator_test
+
+import (
+	"cmp"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+type order struct {
+	ID       int
+	Customer string
+	Amount   int
+}
+
+func ordersContext(opts ...aggregator.Option) context.Context {
+	ctx := aggregator.Register[order](context.Background(), opts...)
+	_ = aggregator.CollectMany(ctx, []order{
+		{ID: 1, Customer: "alice", Amount: 30},
+		{ID: 2, Customer: "bob", Amount: 10},
+		{ID: 3, Customer: "alice", Amount: 50},
+		{ID: 4, Customer: "carol", Amount: 10},
+		{ID: 5, Customer: "bob", Amount: 40},
+	})
+
+	return ctx
+}
+
+func ids(orders []order) []int {
+	ids := make([]int, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+
+	return ids
+}
+
+func byAmount(a, b order) int {
+	return cmp.Compare(a.Amount, b.Amount)
+}
+
+func TestQuery_ToSlice(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := ordersContext(opts...)
+
+			all, err := aggregator.Query[order](ctx).ToSlice()
+			assert.NoError(t, err)
+			assert.Equal(t, []int{1, 2, 3, 4, 5}, ids(all))
+
+			page, err := aggregator.Query[order](ctx).
+				Where(func(o order) bool { return o.Amount > 10 }).
+				OrderBy(byAmount).
+				Offset(1).
+				Limit(2).
+				ToSlice()
+			assert.NoError(t, err)
+			assert.Equal(t, []int{5, 3}, ids(page))
+		})
+	}
+}
+
+func TestQuery_StepsApplyInSQLOrder(t *testing.T) {
+	ctx := ordersContext()
+
+	page, err := aggregator.Query[order](ctx).
+		Limit(2).
+		Offset(1).
+		OrderBy(byAmount).
+		Where(func(o order) bool { return o.Customer != "carol" }).
+		ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 5}, ids(page))
+}
+
+func TestQuery_WhereCombines(t *testing.T) {
+	ctx := ordersContext()
+
+	results, err := aggregator.Query[order](ctx).
+		Where(func(o order) bool { return o.Customer == "alice" }).
+		Where(func(o order) bool { return o.Amount > 40 }).
+		ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3}, ids(results))
+}
+
+func TestQuery_OrderByBreaksTies(t *testing.T) {
+	ctx := ordersContext()
+
+	results, err := aggregator.Query[order](ctx).
+		OrderBy(byAmount).
+		ToSlice()
+	assert.NoError(t, err)
+	// The sort is stable
+	assert.Equal(t, []int{2, 4, 1, 5, 3}, ids(results))
+
+	results, err = aggregator.Query[order](ctx).
+		OrderBy(byAmount).
+		OrderBy(func(a, b order) int { return cmp.Compare(b.ID, a.ID) }).
+		ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{4, 2, 1, 5, 3}, ids(results))
+}
+
+func TestQuery_IsImmutable(t *testing.T) {
+	ctx := ordersContext()
+
+	base := aggregator.Query[order](ctx).Where(func(o order) bool { return o.Customer == "bob" })
+	big := base.Where(func(o order) bool { return o.Amount > 20 })
+	small := base.Where(func(o order) bool { return o.Amount <= 20 })
+
+	bigCount, _ := big.Count()
+	smallCount, _ := small.Count()
+	baseCount, _ := base.Count()
+	assert.Equal(t, 1, bigCount)
+	assert.Equal(t, 1, smallCount)
+	assert.Equal(t, 2, baseCount)
+}
+
+func TestQuery_SnapshotsOnTerminal(t *testing.T) {
+	ctx := ordersContext()
+	q := aggregator.Query[order](ctx)
+
+	n, _ := q.Count()
+	assert.Equal(t, 5, n)
+
+	assert.NoError(t, aggregator.Collect(ctx, order{ID: 6}))
+	n, _ = q.Count()
+	assert.Equal(t, 6, n)
+}
+
+func TestQuery_Limits(t *testing.T) {
+	ctx := ordersContext()
+
+	results, err := aggregator.Query[order](ctx).Limit(0).ToSlice()
+	assert.NoError(t, err)
+	assert.NotNil(t, results)
+	assert.Empty(t, results)
+
+	results, _ = aggregator.Query[order](ctx).Limit(2).Limit(-1).ToSlice()
+	assert.Len(t, results, 5)
+
+	results, _ = aggregator.Query[order](ctx).Offset(-3).ToSlice()
+	assert.Len(t, results, 5)
+
+	results, _ = aggregator.Query[order](ctx).Offset(10).ToSlice()
+	assert.Empty(t, results)
+}
+
+func TestQuery_Count(t *testing.T) {
+	ctx := ordersContext()
+
+	n, err := aggregator.Query[order](ctx).
+		Where(func(o order) bool { return o.Customer == "alice" }).
+		Count()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestQuery_FirstAndLast(t *testing.T) {
+	ctx := ordersContext()
+
+	first, ok, err := aggregator.Query[order](ctx).OrderBy(byAmount).First()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, first.ID)
+
+	last, ok, err := aggregator.Query[order](ctx).OrderBy(byAmount).Last()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, last.ID)
+
+	none := aggregator.Query[order](ctx).Where(func(order) bool { return false })
+	_, ok, err = none.First()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = none.Last()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestQuery_GroupBy(t *testing.T) {
+	ctx := ordersContext()
+
+	groups, err := aggregator.GroupBy(
+		aggregator.Query[order](ctx).OrderBy(byAmount),
+		func(o order) string { return o.Customer },
+	)
+	assert.NoError(t, err)
+	assert.Len(t, groups, 3)
+	assert.Equal(t, []int{1, 3}, ids(groups["alice"]))
+	assert.Equal(t, []int{2, 5}, ids(groups["bob"]))
+	assert.Equal(t, []int{4}, ids(groups["carol"]))
+}
+
+func TestQuery_Reduce(t *testing.T) {
+	ctx := ordersContext()
+
+	total, err := aggregator.Reduce(
+		aggregator.Query[order](ctx).Where(func(o order) bool { return o.Customer == "bob" }),
+		0,
+		func(sum int, o order) int { return sum + o.Amount },
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, total)
+}
+
+func TestQuery_Key(t *testing.T) {
+	key := aggregator.NewKey[int]("numbers")
+	ctx := aggregator.RegisterKey(context.Background(), key)
+	handle, _ := aggregator.LookupKey(ctx, key)
+	assert.NoError(t, handle.CollectMany([]int{3, 1, 2}))
+
+	results, err := aggregator.QueryKey(ctx, key).OrderBy(cmp.Compare[int]).ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, results)
+}
+
+func TestQuery_Errors(t *testing.T) {
+	missing := aggregator.Query[order](context.Background()).Where(func(order) bool { return true })
+
+	_, err := missing.ToSlice()
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+	_, err = missing.Count()
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+	_, _, err = missing.First()
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+	_, _, err = missing.Last()
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+	_, err = aggregator.GroupBy(missing, func(o order) int { return o.ID })
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+	_, err = aggregator.Reduce(missing, 0, func(n int, _ order) int { return n + 1 })
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+
+	ctx := aggregator.RegisterBaseContextAggregator[int](context.Background(), "test")
+	_, err = aggregator.Query[string](ctx, "test").ToSlice()
+	assert.Equal(t, aggregator.ErrInvalidType, err)
+}
@@ -0,0 +1,195 @@
+package aggregator_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+type latencyStats struct {
+	Count int
+	Total time.Duration
+	Max   time.Duration
+}
+
+func addLatency(s latencyStats, d time.Duration) latencyStats {
+	return latencyStats{Count: s.Count + 1, Total: s.Total + d, Max: max(s.Max, d)}
+}
+
+func mergeLatency(a, b latencyStats) latencyStats {
+	return latencyStats{Count: a.Count + b.Count, Total: a.Total + b.Total, Max: max(a.Max, b.Max)}
+}
+
+func sum(total, i int) int {
+	return total + i
+}
+
+func TestReducer_Sum(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.RegisterReducer(context.Background(), 0, sum, opts...)
+			for i := 1; i <= 10; i++ {
+				assert.NoError(t, aggregator.Collect(ctx, i))
+			}
+
+			total, err := aggregator.AggregateReduced[int, int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, 55, total)
+
+			items, err := aggregator.Aggregate[int](ctx)
+			assert.NoError(t, err)
+			assert.Empty(t, items)
+		})
+	}
+}
+
+func TestReducer_ReducePanicReleasesLock(t *testing.T) {
+	agg := aggregator.NewReducer(0, func(total, i int) int { return total + 10/i }, aggregator.WithConcurrency())
+
+	assert.Panics(t, func() { agg.Collect(0) })
+	agg.Collect(5)
+	assert.Equal(t, 2, agg.(aggregator.Reducer[int]).AggregateReduced())
+}
+
+func TestReducer_StructState(t *testing.T) {
+	ctx := aggregator.RegisterReducer(context.Background(), latencyStats{}, addLatency)
+	_ = aggregator.Collect(ctx, 20*time.Millisecond)
+	_ = aggregator.Collect(ctx, 50*time.Millisecond)
+
+	stats, err := aggregator.AggregateReduced[time.Duration, latencyStats](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, latencyStats{Count: 2, Total: 70 * time.Millisecond, Max: 50 * time.Millisecond}, stats)
+}
+
+func TestReducer_Keys(t *testing.T) {
+	ctx := aggregator.RegisterReducer(context.Background(), 0, sum, aggregator.WithKey("sum"))
+	ctx = aggregator.RegisterReducer(ctx, 0, func(n, _ int) int { return n + 1 }, aggregator.WithKey("count"))
+
+	for i := 1; i <= 4; i++ {
+		_ = aggregator.Collect(ctx, i, "sum")
+		_ = aggregator.Collect(ctx, i, "count")
+	}
+
+	total, _ := aggregator.AggregateReduced[int, int](ctx, "sum")
+	count, _ := aggregator.AggregateReduced[int, int](ctx, "count")
+	assert.Equal(t, 10, total)
+	assert.Equal(t, 4, count)
+}
+
+func TestReducer_SameStateDifferentElements(t *testing.T) {
+	ctx := aggregator.RegisterReducer(context.Background(), 0, sum)
+	ctx = aggregator.RegisterReducer(ctx, 0, func(n int, s string) int { return n + len(s) })
+
+	_ = aggregator.Collect(ctx, 3)
+	_ = aggregator.Collect(ctx, "abcde")
+
+	total, err := aggregator.AggregateReduced[int, int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	length, err := aggregator.AggregateReduced[string, int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, length)
+}
+
+func TestReducer_NotFound(t *testing.T) {
+	_, err := aggregator.AggregateReduced[int, int](context.Background())
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+
+	ctx := aggregator.RegisterReducer(context.Background(), 0, sum, aggregator.WithKey("sum"))
+	_, err = aggregator.AggregateReduced[int, string](ctx, "sum")
+	assert.Equal(t, aggregator.ErrInvalidType, err)
+}
+
+func TestReducer_Concurrent(t *testing.T) {
+	ctx := aggregator.RegisterReducer(context.Background(), 0, sum, aggregator.WithConcurrency())
+
+	for i := 1; i <= 100; i++ {
+		ctx, done := aggregator.WaitFuncOf[int](ctx)
+		go func(i int) {
+			defer done()
+			_ = aggregator.Collect(ctx, i)
+		}(i)
+	}
+
+	total, err := aggregator.AggregateReduced[int, int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 5050, total)
+}
+
+func TestReducer_Partials(t *testing.T) {
+	ctx := aggregator.RegisterReducer(context.Background(), latencyStats{}, addLatency,
+		aggregator.WithConcurrency(),
+		aggregator.WithMerge(mergeLatency),
+	)
+
+	for worker := 0; worker < 8; worker++ {
+		ctx, done := aggregator.WaitFuncOf[time.Duration](ctx)
+		go func(worker int) {
+			defer done()
+
+			partial, err := aggregator.NewPartial[time.Duration, latencyStats](ctx)
+			assert.NoError(t, err)
+			for i := 1; i <= 10; i++ {
+				partial.Collect(time.Duration(worker*10+i) * time.Millisecond)
+			}
+			assert.NoError(t, partial.Commit())
+		}(worker)
+	}
+
+	stats, err := aggregator.AggregateReduced[time.Duration, latencyStats](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 80, stats.Count)
+	assert.Equal(t, 80*time.Millisecond, stats.Max)
+	assert.Equal(t, 3240*time.Millisecond, stats.Total)
+}
+
+func TestReducer_PartialWithoutMerge(t *testing.T) {
+	ctx := aggregator.RegisterReducer(context.Background(), 0, sum)
+
+	_, err := aggregator.NewPartial[int, int](ctx)
+	assert.Equal(t, aggregator.ErrNotMergeable, err)
+
+	_, err = aggregator.NewPartial[int, string](ctx)
+	assert.Equal(t, aggregator.ErrInvalidType, err)
+}
+
+func TestReducer_Seal(t *testing.T) {
+	ctx := aggregator.RegisterReducer(context.Background(), 0, sum, aggregator.WithMerge(sum))
+	partial, err := aggregator.NewPartial[int, int](ctx)
+	assert.NoError(t, err)
+	partial.Collect(1)
+	partial.Collect(2)
+
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+	assert.NoError(t, aggregator.Seal[int](ctx))
+	assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, 1))
+	assert.Equal(t, aggregator.ErrAggregatorClosed, partial.Commit())
+
+	total, _ := aggregator.AggregateReduced[int, int](ctx)
+	assert.Equal(t, 1, total)
+	late, _ := aggregator.LateCollects[int](ctx)
+	assert.Equal(t, 3, late)
+}
+
+func TestReducer_MergeTypeMismatch(t *testing.T) {
+	assert.Panics(t, func() {
+		aggregator.RegisterReducer(context.Background(), 0, sum,
+			aggregator.WithMerge(func(a, b string) string { return a + b }),
+		)
+	})
+}
+
+func TestReducer_Inspect(t *testing.T) {
+	ctx := aggregator.RegisterReducer(context.Background(), 0, sum)
+	_ = aggregator.Collect(ctx, 1)
+	_ = aggregator.Collect(ctx, 2)
+
+	infos := aggregator.Inspect(ctx)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, aggregator.KindReducer, infos[0].Kind)
+	assert.Equal(t, 2, infos[0].Len)
+}
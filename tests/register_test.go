@@ -0,0 +1,93 @@
+package aggregator_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestRegister_Default(t *testing.T) {
+	ctx := aggregator.Register[string](context.Background())
+
+	_ = aggregator.Collect(ctx, "a")
+	_ = aggregator.Collect(ctx, "b")
+
+	results, err := aggregator.Aggregate[string](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, results)
+}
+
+func TestRegister_WithKey(t *testing.T) {
+	ctx := context.Background()
+	ctx = aggregator.Register[string](ctx, aggregator.WithKey("errors"))
+	ctx = aggregator.Register[string](ctx, aggregator.WithKey("warnings"))
+
+	_ = aggregator.Collect(ctx, "error", "errors")
+	_ = aggregator.Collect(ctx, "warning", "warnings")
+
+	errs, err := aggregator.Aggregate[string](ctx, "errors")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"error"}, errs)
+
+	warnings, err := aggregator.Aggregate[string](ctx, "warnings")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"warning"}, warnings)
+}
+
+func TestRegister_WithConcurrencyAndCapacity(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithConcurrency(),
+		aggregator.WithCapacity(100),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(val int) {
+			defer wg.Done()
+			_ = aggregator.Collect(ctx, val)
+		}(i)
+	}
+	wg.Wait()
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+	assert.Len(t, results, 100)
+}
+
+func TestRegister_WithCallbackAndConcurrency(t *testing.T) {
+	var callbackCount int32
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithConcurrency(),
+		aggregator.WithCallback(func(int) {
+			atomic.AddInt32(&callbackCount, 1)
+		}),
+		aggregator.WithKey("stream"),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(val int) {
+			defer wg.Done()
+			_ = aggregator.Collect(ctx, val, "stream")
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(10), atomic.LoadInt32(&callbackCount))
+
+	results, err := aggregator.Aggregate[int](ctx, "stream")
+	assert.NoError(t, err)
+	assert.Len(t, results, 10)
+}
+
+func TestRegister_CallbackTypeMismatch(t *testing.T) {
+	assert.Panics(t, func() {
+		aggregator.Register[int](context.Background(), aggregator.WithCallback(func(string) {}))
+	})
+}
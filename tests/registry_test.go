@@ -0,0 +1,173 @@
+package aggregator_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+type chainKey string
+
+func TestRegistry_ManyAggregators(t *testing.T) {
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		ctx = aggregator.RegisterBaseContextAggregator[int](ctx, fmt.Sprint(i))
+	}
+
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, aggregator.Collect(ctx, i, fmt.Sprint(i)))
+	}
+
+	for i := 0; i < 20; i++ {
+		results, err := aggregator.Aggregate[int](ctx, fmt.Sprint(i))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{i}, results)
+	}
+}
+
+func TestRegistry_ParentDoesNotSeeChildRegistration(t *testing.T) {
+	parent := aggregator.RegisterBaseContextAggregator[int](context.Background())
+	child := aggregator.RegisterBaseContextAggregator[string](parent)
+
+	assert.NoError(t, aggregator.Collect(child, 1))
+	assert.NoError(t, aggregator.Collect(child, "a"))
+
+	err := aggregator.Collect(parent, "b")
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+
+	// Aggregators registered before the child are shared with it
+	results, err := aggregator.Aggregate[int](parent)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, results)
+}
+
+func TestRegistry_SiblingsAreIndependent(t *testing.T) {
+	root := aggregator.RegisterBaseContextAggregator[int](context.Background())
+	left := aggregator.RegisterBaseContextAggregator[string](root, "side")
+	right := aggregator.RegisterBaseContextAggregator[string](root, "side")
+
+	_ = aggregator.Collect(left, "left", "side")
+	_ = aggregator.Collect(right, "right", "side")
+
+	leftResults, _ := aggregator.Aggregate[string](left, "side")
+	rightResults, _ := aggregator.Aggregate[string](right, "side")
+	assert.Equal(t, []string{"left"}, leftResults)
+	assert.Equal(t, []string{"right"}, rightResults)
+}
+
+func TestRegistry_ShadowingAcrossLayers(t *testing.T) {
+	ctx := context.Background()
+	var contexts []context.Context
+	for i := 0; i < 50; i++ {
+		ctx = aggregator.RegisterBaseContextAggregator[int](ctx, fmt.Sprint(i%7))
+		assert.NoError(t, aggregator.Collect(ctx, i, fmt.Sprint(i%7)))
+		contexts = append(contexts, ctx)
+	}
+
+	// Every key resolves to its latest registration
+	for key := 0; key < 7; key++ {
+		results, err := aggregator.Aggregate[int](ctx, fmt.Sprint(key))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{49 - (49-key)%7}, results)
+	}
+	assert.Len(t, aggregator.Inspect(ctx), 7)
+
+	// Earlier contexts keep their view once layers were merged
+	for i, ctx := range contexts {
+		results, err := aggregator.Aggregate[int](ctx, fmt.Sprint(i%7))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{i}, results)
+		assert.Len(t, aggregator.Inspect(ctx), min(i+1, 7))
+	}
+}
+
+func TestRegistry_UnrelatedContextValues(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregator[int](context.Background())
+	ctx = context.WithValue(ctx, chainKey("request-id"), "abc")
+	ctx = aggregator.RegisterBaseContextAggregator[string](ctx)
+
+	assert.NoError(t, aggregator.Collect(ctx, 1))
+	assert.NoError(t, aggregator.Collect(ctx, "a"))
+	assert.Equal(t, "abc", ctx.Value(chainKey("request-id")))
+}
+
+// buildChainContext registers n aggregators and returns the key of the first
+// one, which is the deepest in a context.WithValue chain
+func buildChainContext(n int) (context.Context, string) {
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		ctx = aggregator.RegisterBaseContextAggregatorWithCapacity[int](ctx, 1024, fmt.Sprint(i))
+	}
+
+	return ctx, "0"
+}
+
+func BenchmarkLookup_Registry(b *testing.B) {
+	for _, n := range []int{1, 10, 20} {
+		b.Run(fmt.Sprintf("aggregators=%d", n), func(b *testing.B) {
+			ctx, key := buildChainContext(n)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = aggregator.Aggregate[int](ctx, key)
+			}
+		})
+	}
+}
+
+func BenchmarkRegister_Many(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("aggregators=%d", n), func(b *testing.B) {
+			keys := make([]string, n)
+			for i := range keys {
+				keys[i] = fmt.Sprint(i)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ctx := context.Background()
+				for _, key := range keys {
+					ctx = aggregator.RegisterBaseContextAggregator[int](ctx, key)
+				}
+			}
+		})
+	}
+}
+
+// extractFromChain replicates the previous lookup, where every aggregator was
+// its own context.WithValue layer and a lookup walked the chain
+func extractFromChain[T any](ctx context.Context, keys ...string) (aggregator.ContextAggregator[T], error) {
+	keys = append([]string{"ctxAggCtxKey"}, keys...)
+	aggVal := ctx.Value(chainKey(strings.Join(keys, "_")))
+	if aggVal == nil {
+		return nil, aggregator.ErrNotFoundAggregator
+	}
+
+	agg, ok := aggVal.(aggregator.ContextAggregator[T])
+	if !ok {
+		return nil, aggregator.ErrInvalidType
+	}
+
+	return agg, nil
+}
+
+func BenchmarkLookup_ValueChain(b *testing.B) {
+	for _, n := range []int{1, 10, 20} {
+		b.Run(fmt.Sprintf("aggregators=%d", n), func(b *testing.B) {
+			ctx := context.Background()
+			for i := 0; i < n; i++ {
+				ctx = context.WithValue(ctx, chainKey("ctxAggCtxKey_"+fmt.Sprint(i)), aggregator.NewBase[int]())
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				agg, _ := extractFromChain[int](ctx, "0")
+				_ = agg.Aggregate()
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package aggregator_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestReservoir_KeepsEverythingUntilFull(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.RegisterReservoirAggregator[int](context.Background(), 10, opts...)
+			for i := 0; i < 5; i++ {
+				assert.NoError(t, aggregator.Collect(ctx, i))
+			}
+
+			sample, seen, err := aggregator.AggregateSample[int](ctx)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []int{0, 1, 2, 3, 4}, sample)
+			assert.Equal(t, 5, seen)
+		})
+	}
+}
+
+func TestReservoir_SampleSizeAndSeen(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.RegisterReservoirAggregator[int](context.Background(), 10, opts...)
+			for i := 0; i < 100_000; i++ {
+				_ = aggregator.Collect(ctx, i)
+			}
+
+			sample, seen, err := aggregator.AggregateSample[int](ctx)
+			assert.NoError(t, err)
+			assert.Len(t, sample, 10)
+			assert.Equal(t, 100_000, seen)
+
+			distinct := make(map[int]bool)
+			for _, item := range sample {
+				assert.True(t, item >= 0 && item < 100_000)
+				distinct[item] = true
+			}
+			assert.Len(t, distinct, 10)
+		})
+	}
+}
+
+func TestReservoir_DeterministicWithSeed(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, aggregator.WithSeed(42))
+			first := aggregator.NewReservoir[int](5, opts...)
+			second := aggregator.NewReservoir[int](5, opts...)
+			for i := 0; i < 1000; i++ {
+				first.Collect(i)
+				second.Collect(i)
+			}
+
+			assert.Equal(t, first.Aggregate(), second.Aggregate())
+		})
+	}
+}
+
+// Every item should be sampled with the same probability size/n, including
+// after merging the per-P reservoirs of the concurrent variant
+func TestReservoir_Uniform(t *testing.T) {
+	const (
+		size   = 10
+		n      = 100
+		trials = 4000
+	)
+
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			counts := make([]int, n)
+			for trial := 0; trial < trials; trial++ {
+				agg := aggregator.NewReservoir[int](size, append(opts, aggregator.WithSeed(uint64(trial)))...)
+				for i := 0; i < n; i++ {
+					agg.Collect(i)
+				}
+				for _, item := range agg.Aggregate() {
+					counts[item]++
+				}
+			}
+
+			// Expected 400 per item with a standard deviation of about 19
+			for item, count := range counts {
+				assert.InDelta(t, trials*size/n, count, 100, "item %d", item)
+			}
+		})
+	}
+}
+
+func TestReservoir_ConcurrentCollect(t *testing.T) {
+	ctx := aggregator.RegisterReservoirAggregator[int](context.Background(), 20, aggregator.WithConcurrency())
+
+	for i := 0; i < 500; i++ {
+		ctx, done := aggregator.WaitFuncOf[int](ctx)
+		go func(i int) {
+			defer done()
+			_ = aggregator.Collect(ctx, i)
+		}(i)
+	}
+
+	sample, seen, err := aggregator.AggregateSample[int](ctx)
+	assert.NoError(t, err)
+	assert.Len(t, sample, 20)
+	assert.Equal(t, 500, seen)
+}
+
+func TestReservoir_DrainAndSeal(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.RegisterReservoirAggregator[int](context.Background(), 3, opts...)
+			for i := 0; i < 10; i++ {
+				_ = aggregator.Collect(ctx, i)
+			}
+
+			drained, err := aggregator.Drain[int](ctx)
+			assert.NoError(t, err)
+			assert.Len(t, drained, 3)
+
+			_, seen, _ := aggregator.AggregateSample[int](ctx)
+			assert.Zero(t, seen)
+
+			assert.NoError(t, aggregator.Seal[int](ctx))
+			assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, 1))
+
+			late, err := aggregator.LateCollects[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, late)
+		})
+	}
+}
+
+func TestReservoir_Inspect(t *testing.T) {
+	ctx := aggregator.RegisterReservoirAggregator[int](context.Background(), 4)
+	for i := 0; i < 10; i++ {
+		_ = aggregator.Collect(ctx, i)
+	}
+
+	infos := aggregator.Inspect(ctx)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, aggregator.KindReservoir, infos[0].Kind)
+	assert.Equal(t, 4, infos[0].Len)
+	assert.Equal(t, 6, infos[0].Dropped)
+}
+
+func TestReservoir_NonSampledAggregator(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background())
+	_ = aggregator.Collect(ctx, 1)
+	_ = aggregator.Collect(ctx, 2)
+
+	sample, seen, err := aggregator.AggregateSample[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, sample)
+	assert.Equal(t, 2, seen)
+}
+
+func BenchmarkReservoir_Collect(b *testing.B) {
+	agg := aggregator.NewReservoir[int](100)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		agg.Collect(i)
+	}
+}
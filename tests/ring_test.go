@@ -0,0 +1,134 @@
+package aggregator_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+// concurrencyFlavors lists the options of the sequential and concurrent
+// variants of an aggregator, for tests covering both
+func concurrencyFlavors() map[string][]aggregator.Option {
+	return map[string][]aggregator.Option{
+		"sequential": nil,
+		"concurrent": {aggregator.WithConcurrency()},
+	}
+}
+
+func TestRing_KeepsLastItemsOldestFirst(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.RegisterRingAggregator[int](context.Background(), 3, opts...)
+
+			for i := 0; i < 10; i++ {
+				assert.NoError(t, aggregator.Collect(ctx, i))
+			}
+
+			results, dropped, err := aggregator.AggregateWithDropped[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{7, 8, 9}, results)
+			assert.Equal(t, 7, dropped)
+		})
+	}
+}
+
+func TestRing_NotFull(t *testing.T) {
+	ctx := aggregator.RegisterRingAggregator[int](context.Background(), 5)
+	_ = aggregator.Collect(ctx, 1)
+	_ = aggregator.Collect(ctx, 2)
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, results)
+}
+
+func TestRing_FilterAndTransform(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, aggregator.WithKey("events"))
+			ctx := aggregator.RegisterRingAggregator[int](context.Background(), 4, opts...)
+			for i := 0; i < 6; i++ {
+				_ = aggregator.Collect(ctx, i, "events")
+			}
+
+			even, err := aggregator.AggregateWithFilter(ctx, func(i int) bool { return i%2 == 0 }, "events")
+			assert.NoError(t, err)
+			assert.Equal(t, []int{2, 4}, even)
+
+			strs, err := aggregator.AggregateWithTransform(ctx, strconv.Itoa, "events")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"2", "3", "4", "5"}, strs)
+		})
+	}
+}
+
+func TestRing_Concurrent(t *testing.T) {
+	ctx := aggregator.RegisterRingAggregator[int](context.Background(), 50, aggregator.WithConcurrency())
+
+	for i := 0; i < 200; i++ {
+		ctx, done := aggregator.WaitFuncOf[int](ctx)
+		go func(i int) {
+			defer done()
+			_ = aggregator.Collect(ctx, i)
+		}(i)
+	}
+
+	results, dropped, err := aggregator.AggregateWithDropped[int](ctx)
+	assert.NoError(t, err)
+	assert.Len(t, results, 50)
+	assert.Equal(t, 150, dropped)
+}
+
+func TestRing_NoAllocationsAfterWarmup(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			agg := aggregator.NewRing[int](8, opts...)
+			for i := 0; i < 8; i++ {
+				agg.Collect(i)
+			}
+
+			allocs := testing.AllocsPerRun(100, func() {
+				agg.Collect(1)
+			})
+			assert.Zero(t, allocs)
+		})
+	}
+}
+
+func TestRing_SealAndDrain(t *testing.T) {
+	ctx := aggregator.RegisterRingAggregator[int](context.Background(), 2)
+	_ = aggregator.Collect(ctx, 1)
+
+	drained, err := aggregator.Drain[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, drained)
+
+	_ = aggregator.Collect(ctx, 2)
+	results, err := aggregator.AggregateAndSeal[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, results)
+	assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, 3))
+}
+
+func TestRing_Inspect(t *testing.T) {
+	ctx := aggregator.RegisterRingAggregator[int](context.Background(), 2, aggregator.WithConcurrency())
+	for i := 0; i < 3; i++ {
+		_ = aggregator.Collect(ctx, i)
+	}
+
+	infos := aggregator.Inspect(ctx)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, aggregator.KindConcurrentRing, infos[0].Kind)
+	assert.Equal(t, 2, infos[0].Len)
+	assert.Equal(t, 2, infos[0].Cap)
+	assert.Equal(t, 1, infos[0].Dropped)
+}
+
+func TestRing_InvalidSize(t *testing.T) {
+	assert.Panics(t, func() {
+		aggregator.NewRing[int](0)
+	})
+}
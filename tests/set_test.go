@@ -0,0 +1,153 @@
+package aggregator_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+// concurrencyFlavors returns the options of the sequential and concurrent
+// variants of the special aggregators
+type flagEvaluation struct {
+	Flag    string
+	Enabled bool
+}
+
+func flagName(e flagEvaluation) string {
+	return e.Flag
+}
+
+func TestSet_KeepFirst(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.RegisterSetAggregator(context.Background(), flagName, opts...)
+
+			_ = aggregator.Collect(ctx, flagEvaluation{"b", true})
+			_ = aggregator.Collect(ctx, flagEvaluation{"a", true})
+			_ = aggregator.Collect(ctx, flagEvaluation{"b", false})
+
+			results, dropped, err := aggregator.AggregateWithDropped[flagEvaluation](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []flagEvaluation{{"b", true}, {"a", true}}, results)
+			assert.Equal(t, 1, dropped)
+		})
+	}
+}
+
+func TestSet_KeepLast(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, aggregator.WithDuplicatePolicy(aggregator.KeepLast))
+			ctx := aggregator.RegisterSetAggregator(context.Background(), flagName, opts...)
+
+			_ = aggregator.Collect(ctx, flagEvaluation{"b", true})
+			_ = aggregator.Collect(ctx, flagEvaluation{"a", true})
+			_ = aggregator.Collect(ctx, flagEvaluation{"b", false})
+
+			results, err := aggregator.Aggregate[flagEvaluation](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []flagEvaluation{{"b", false}, {"a", true}}, results)
+		})
+	}
+}
+
+func TestSet_KeyFnPanicReleasesLock(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			agg := aggregator.NewSet(func(s string) string {
+				if s == "" {
+					panic("empty key")
+				}
+				return s
+			}, opts...)
+
+			assert.Panics(t, func() { agg.Collect("") })
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				agg.Collect("a")
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("collect blocked after the key function panicked")
+			}
+			assert.Equal(t, []string{"a"}, agg.Aggregate())
+		})
+	}
+}
+
+func TestSet_IdentityKey(t *testing.T) {
+	ctx := aggregator.RegisterSetAggregator(context.Background(), func(s string) string { return s },
+		aggregator.WithKey("tables"),
+	)
+
+	for _, table := range []string{"users", "orders", "users", "users", "items"} {
+		_ = aggregator.Collect(ctx, table, "tables")
+	}
+
+	tables, err := aggregator.Aggregate[string](ctx, "tables")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users", "orders", "items"}, tables)
+}
+
+func TestSet_Concurrent(t *testing.T) {
+	ctx := aggregator.RegisterSetAggregator(context.Background(), func(i int) int { return i % 10 },
+		aggregator.WithConcurrency(),
+	)
+
+	for i := 0; i < 200; i++ {
+		ctx, done := aggregator.WaitFuncOf[int](ctx)
+		go func(i int) {
+			defer done()
+			_ = aggregator.Collect(ctx, i)
+		}(i)
+	}
+
+	results, dropped, err := aggregator.AggregateWithDropped[int](ctx)
+	assert.NoError(t, err)
+	assert.Len(t, results, 10)
+	assert.Equal(t, 190, dropped)
+}
+
+func TestSet_DrainForgetsKeys(t *testing.T) {
+	ctx := aggregator.RegisterSetAggregator(context.Background(), func(s string) string { return s })
+	_ = aggregator.Collect(ctx, "a")
+
+	drained, err := aggregator.Drain[string](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, drained)
+
+	_ = aggregator.Collect(ctx, "a")
+	results, _ := aggregator.Aggregate[string](ctx)
+	assert.Equal(t, []string{"a"}, results)
+}
+
+func TestSet_Seal(t *testing.T) {
+	ctx := aggregator.RegisterSetAggregator(context.Background(), func(s string) string { return s },
+		aggregator.WithConcurrency(),
+	)
+	_ = aggregator.Collect(ctx, "a")
+
+	results, err := aggregator.AggregateAndSeal[string](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, results)
+	assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, "b"))
+}
+
+func TestSet_Inspect(t *testing.T) {
+	ctx := aggregator.RegisterSetAggregator(context.Background(), func(s string) string { return s })
+	_ = aggregator.Collect(ctx, "a")
+	_ = aggregator.Collect(ctx, "a")
+
+	infos := aggregator.Inspect(ctx)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, aggregator.KindSet, infos[0].Kind)
+	assert.Equal(t, "string", infos[0].ElemType)
+	assert.Equal(t, 1, infos[0].Len)
+	assert.Equal(t, 1, infos[0].Dropped)
+}
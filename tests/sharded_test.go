@@ -0,0 +1,179 @@
+package aggregator_test
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestSharded_KeepsCollectOrder(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithShards(8))
+
+	want := make([]int, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, aggregator.Collect(ctx, i))
+		want = append(want, i)
+	}
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, want, results)
+}
+
+func TestSharded_KeepsCollectOrderWithBatches(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithShards(4))
+
+	assert.NoError(t, aggregator.Collect(ctx, 0))
+	assert.NoError(t, aggregator.CollectMany(ctx, intRange(300)[1:299]))
+	assert.NoError(t, aggregator.Collect(ctx, 299))
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, intRange(300), results)
+}
+
+// Concurrent collectors move across shards and reserve many blocks, the merge
+// must neither lose nor duplicate any of their items
+func TestSharded_ConcurrentKeepsEveryItem(t *testing.T) {
+	const goroutines, perGoroutine = 8, 1000
+	agg := aggregator.New[[2]int](aggregator.WithShards(4)).(aggregator.ConcurrentContextAggregator[[2]int])
+
+	for g := 0; g < goroutines; g++ {
+		agg.AddWait()
+		go func(g int) {
+			defer agg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				agg.Collect([2]int{g, i})
+			}
+		}(g)
+	}
+
+	results := agg.Aggregate()
+	assert.Len(t, results, goroutines*perGoroutine)
+
+	seen := make(map[[2]int]bool, len(results))
+	for _, item := range results {
+		seen[item] = true
+	}
+	assert.Len(t, seen, goroutines*perGoroutine)
+}
+
+func TestSharded_WaitFunc(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithShards(0))
+
+	for i := 0; i < 500; i++ {
+		ctx, done := aggregator.WaitFuncOf[int](ctx)
+		go func(i int) {
+			defer done()
+			_ = aggregator.Collect(ctx, i)
+		}(i)
+	}
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+	assert.Len(t, results, 500)
+	assert.ElementsMatch(t, intRange(500), results)
+}
+
+func TestSharded_ConcurrentContextAggregator(t *testing.T) {
+	agg, ok := aggregator.New[int](aggregator.WithShards(4)).(aggregator.ConcurrentContextAggregator[int])
+	assert.True(t, ok)
+
+	for i := 0; i < 100; i++ {
+		agg.AddWait()
+		go func(i int) {
+			defer agg.Done()
+			agg.Collect(i)
+		}(i)
+	}
+
+	assert.Len(t, agg.Aggregate(), 100)
+}
+
+func TestSharded_SealAndDrain(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithShards(4))
+	for i := 0; i < 10; i++ {
+		_ = aggregator.Collect(ctx, i)
+	}
+
+	drained, err := aggregator.Drain[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, intRange(10), drained)
+
+	_ = aggregator.Collect(ctx, 42)
+	results, err := aggregator.AggregateAndSeal[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{42}, results)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, i))
+	}
+	late, _ := aggregator.LateCollects[int](ctx)
+	assert.Equal(t, 10, late)
+}
+
+func TestSharded_FallsBackWithMaxItems(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(),
+		aggregator.WithShards(4),
+		aggregator.WithMaxItems(2, aggregator.OverflowDropNewest),
+	)
+	for i := 0; i < 5; i++ {
+		_ = aggregator.Collect(ctx, i)
+	}
+
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, []int{0, 1}, results)
+	assert.Equal(t, aggregator.KindConcurrent, aggregator.Inspect(ctx)[0].Kind)
+}
+
+func TestSharded_Inspect(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background(), aggregator.WithShards(4))
+	for i := 0; i < 10; i++ {
+		_ = aggregator.Collect(ctx, i)
+	}
+
+	infos := aggregator.Inspect(ctx)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, aggregator.KindSharded, infos[0].Kind)
+	assert.Equal(t, 10, infos[0].Len)
+}
+
+func intRange(n int) []int {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+
+	return items
+}
+
+// Compare with: go test -bench Contended -run ^$ ./tests/
+func BenchmarkContended(b *testing.B) {
+	flavors := []struct {
+		name string
+		opts []aggregator.Option
+	}{
+		{"mutex", []aggregator.Option{aggregator.WithConcurrency()}},
+		{"sharded", []aggregator.Option{aggregator.WithShards(0)}},
+	}
+
+	for _, procs := range []int{1, 4, 16, 64} {
+		for _, flavor := range flavors {
+			b.Run(fmt.Sprintf("%s/procs=%d", flavor.name, procs), func(b *testing.B) {
+				defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+				agg := aggregator.New[int](flavor.opts...)
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						agg.Collect(1)
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package aggregator_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestSnapshot_ModifyingResultDoesNotCorruptAggregator(t *testing.T) {
+	flavors := map[string][]aggregator.Option{
+		"base":                 nil,
+		"concurrent":           {aggregator.WithConcurrency()},
+		"streaming":            {aggregator.WithCallback(func(int) {})},
+		"concurrent streaming": {aggregator.WithConcurrency(), aggregator.WithCallback(func(int) {})},
+	}
+
+	for name, opts := range flavors {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.Register[int](context.Background(), opts...)
+			_ = aggregator.Collect(ctx, 1)
+			_ = aggregator.Collect(ctx, 2)
+
+			results, err := aggregator.Aggregate[int](ctx)
+			assert.NoError(t, err)
+			results[0] = 100
+
+			results, err = aggregator.Aggregate[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{1, 2}, results)
+		})
+	}
+}
+
+func TestSnapshot_LaterCollectDoesNotChangeResult(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregatorWithCapacity[int](context.Background(), 10)
+	_ = aggregator.Collect(ctx, 1)
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+
+	_ = aggregator.Collect(ctx, 2)
+	assert.Equal(t, []int{1}, results)
+	assert.Equal(t, []int{1}, results[:cap(results)])
+}
+
+func TestSnapshot_AggregateUnsafe(t *testing.T) {
+	ctx := aggregator.RegisterBaseContextAggregator[int](context.Background())
+	_ = aggregator.Collect(ctx, 1)
+
+	results, err := aggregator.AggregateUnsafe[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, results)
+
+	// The zero-copy view shares storage with the aggregator
+	results[0] = 100
+	snapshot, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, []int{100}, snapshot)
+
+	handle, _ := aggregator.Lookup[int](ctx)
+	assert.Equal(t, []int{100}, handle.AggregateUnsafe())
+
+	_, err = aggregator.AggregateUnsafe[int](context.Background())
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+}
+
+func TestSnapshot_AggregateUnsafeCustomAggregator(t *testing.T) {
+	ctx := aggregator.RegisterAggregator[string](context.Background(), &upperAggregator{})
+	_ = aggregator.Collect(ctx, "a")
+
+	results, err := aggregator.AggregateUnsafe[string](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A"}, results)
+}
+
+func TestSnapshot_CollectWhileReadingIsRaceFree(t *testing.T) {
+	flavors := map[string][]aggregator.Option{
+		"concurrent":           {aggregator.WithConcurrency()},
+		"concurrent streaming": {aggregator.WithConcurrency(), aggregator.WithCallback(func(int) {})},
+	}
+
+	for name, opts := range flavors {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.Register[int](context.Background(), opts...)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 500; j++ {
+						_ = aggregator.Collect(ctx, j)
+					}
+				}()
+			}
+
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						results, err := aggregator.Aggregate[int](ctx)
+						assert.NoError(t, err)
+						for k := range results {
+							results[k]++
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			results, err := aggregator.Aggregate[int](ctx)
+			assert.NoError(t, err)
+			assert.Len(t, results, 2000)
+
+			sum := 0
+			for _, v := range results {
+				sum += v
+			}
+			assert.Equal(t, 4*(499*500/2), sum)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package aggregator_test
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func TestStats_Moments(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.RegisterStatsAggregator[float64](context.Background(), opts...)
+			for _, v := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+				assert.NoError(t, aggregator.Collect(ctx, v))
+			}
+
+			summary, err := aggregator.AggregateSummary[float64](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, 8, summary.Count)
+			assert.Equal(t, 40.0, summary.Sum)
+			assert.Equal(t, 2.0, summary.Min)
+			assert.Equal(t, 9.0, summary.Max)
+			assert.InDelta(t, 5.0, summary.Mean, 1e-9)
+			assert.InDelta(t, 4.0, summary.Variance, 1e-9)
+			assert.InDelta(t, 2.0, summary.StdDev(), 1e-9)
+		})
+	}
+}
+
+func TestStats_Quantiles(t *testing.T) {
+	ctx := aggregator.RegisterStatsAggregator[time.Duration](context.Background())
+
+	latencies := make([]time.Duration, 0, 10_000)
+	for i := 1; i <= 10_000; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	rand.New(rand.NewPCG(1, 2)).Shuffle(len(latencies), func(i, j int) {
+		latencies[i], latencies[j] = latencies[j], latencies[i]
+	})
+	for _, latency := range latencies {
+		_ = aggregator.Collect(ctx, latency)
+	}
+
+	summary, err := aggregator.AggregateSummary[time.Duration](ctx)
+	assert.NoError(t, err)
+
+	for q, want := range map[float64]time.Duration{
+		0.5:  5000 * time.Millisecond,
+		0.95: 9500 * time.Millisecond,
+		0.99: 9900 * time.Millisecond,
+	} {
+		got := summary.Quantile(q)
+		assert.InEpsilon(t, float64(want), float64(got), 0.011, "p%v", q*100)
+	}
+	assert.Equal(t, time.Millisecond, summary.Quantile(0))
+	assert.Equal(t, 10_000*time.Millisecond, summary.Quantile(1))
+}
+
+func TestStats_RelativeAccuracy(t *testing.T) {
+	ctx := aggregator.RegisterStatsAggregator[float64](context.Background(),
+		aggregator.WithRelativeAccuracy(0.001),
+	)
+
+	values := make([]float64, 0, 5000)
+	r := rand.New(rand.NewPCG(3, 4))
+	for i := 0; i < 5000; i++ {
+		v := r.ExpFloat64() * 100
+		values = append(values, v)
+		_ = aggregator.Collect(ctx, v)
+	}
+	slices.Sort(values)
+
+	summary, _ := aggregator.AggregateSummary[float64](ctx)
+	for _, q := range []float64{0.1, 0.5, 0.9, 0.99} {
+		want := values[int(q*float64(len(values)-1))]
+		assert.InEpsilon(t, want, summary.Quantile(q), 0.001, "q=%v", q)
+	}
+}
+
+func TestStats_NegativeAndZero(t *testing.T) {
+	ctx := aggregator.RegisterStatsAggregator[int](context.Background())
+	for _, v := range []int{-100, -10, 0, 0, 10, 100, 1000} {
+		_ = aggregator.Collect(ctx, v)
+	}
+
+	summary, _ := aggregator.AggregateSummary[int](ctx)
+	assert.Equal(t, -100, summary.Quantile(0))
+	assert.InDelta(t, -10, summary.Quantile(1.0/6), 1)
+	assert.Equal(t, 0, summary.Quantile(0.5))
+	assert.InDelta(t, 100, summary.Quantile(5.0/6), 2)
+	assert.Equal(t, 1000, summary.Quantile(1))
+}
+
+func TestStats_Empty(t *testing.T) {
+	ctx := aggregator.RegisterStatsAggregator[int](context.Background())
+
+	summary, err := aggregator.AggregateSummary[int](ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, summary.Count)
+	assert.True(t, math.IsNaN(summary.Mean))
+	assert.Zero(t, summary.Quantile(0.5))
+}
+
+func TestStats_Merge(t *testing.T) {
+	first := aggregator.NewStats[float64]()
+	second := aggregator.NewStats[float64]()
+	all := aggregator.NewStats[float64]()
+	for i := 1; i <= 1000; i++ {
+		v := float64(i)
+		if i%3 == 0 {
+			first.Collect(v)
+		} else {
+			second.Collect(v)
+		}
+		all.Collect(v)
+	}
+
+	merged := first.(aggregator.SummaryAggregator[float64]).AggregateSummary().
+		Merge(second.(aggregator.SummaryAggregator[float64]).AggregateSummary())
+	want := all.(aggregator.SummaryAggregator[float64]).AggregateSummary()
+
+	assert.Equal(t, want.Count, merged.Count)
+	assert.Equal(t, want.Sum, merged.Sum)
+	assert.Equal(t, want.Min, merged.Min)
+	assert.Equal(t, want.Max, merged.Max)
+	assert.InDelta(t, want.Mean, merged.Mean, 1e-9)
+	assert.InDelta(t, want.Variance, merged.Variance, 1e-6)
+	assert.Equal(t, want.Quantile(0.9), merged.Quantile(0.9))
+}
+
+func TestStats_Concurrent(t *testing.T) {
+	ctx := aggregator.RegisterStatsAggregator[int](context.Background(), aggregator.WithConcurrency())
+
+	for i := 1; i <= 100; i++ {
+		ctx, done := aggregator.WaitFuncOf[int](ctx)
+		go func(i int) {
+			defer done()
+			_ = aggregator.Collect(ctx, i)
+		}(i)
+	}
+
+	summary, err := aggregator.AggregateSummary[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, summary.Count)
+	assert.Equal(t, 5050, summary.Sum)
+	assert.InDelta(t, 50.5, summary.Mean, 1e-9)
+}
+
+func TestStats_SummaryOfRegularAggregator(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background())
+	for i := 1; i <= 5; i++ {
+		_ = aggregator.Collect(ctx, i)
+	}
+
+	summary, err := aggregator.AggregateSummary[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, summary.Count)
+	assert.Equal(t, 3, summary.Quantile(0.5))
+}
+
+func TestStats_Seal(t *testing.T) {
+	ctx := aggregator.RegisterStatsAggregator[int](context.Background())
+	_ = aggregator.Collect(ctx, 1)
+
+	assert.NoError(t, aggregator.Seal[int](ctx))
+	assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, 2))
+
+	summary, _ := aggregator.AggregateSummary[int](ctx)
+	assert.Equal(t, 1, summary.Count)
+}
+
+func BenchmarkStats_Collect(b *testing.B) {
+	agg := aggregator.NewStats[time.Duration]()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		agg.Collect(time.Duration(i%100_000) * time.Microsecond)
+	}
+}
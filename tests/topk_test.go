@@ -0,0 +1,161 @@
+package aggregator_test
+
+import (
+	"cmp"
+	"context"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+type query struct {
+	SQL      string
+	Duration time.Duration
+}
+
+func byDuration(a, b query) int {
+	return cmp.Compare(a.Duration, b.Duration)
+}
+
+func TestTopK_KeepsGreatestSorted(t *testing.T) {
+	for name, opts := range concurrencyFlavors() {
+		t.Run(name, func(t *testing.T) {
+			ctx := aggregator.RegisterTopKAggregator(context.Background(), 3, cmp.Compare[int], opts...)
+
+			values := rand.New(rand.NewPCG(1, 2)).Perm(100)
+			for _, v := range values {
+				assert.NoError(t, aggregator.Collect(ctx, v))
+			}
+
+			results, dropped, err := aggregator.AggregateWithDropped[int](ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{99, 98, 97}, results)
+			assert.Equal(t, 97, dropped)
+		})
+	}
+}
+
+func TestTopK_FewerThanK(t *testing.T) {
+	ctx := aggregator.RegisterTopKAggregator(context.Background(), 5, cmp.Compare[int])
+	_ = aggregator.Collect(ctx, 2)
+	_ = aggregator.Collect(ctx, 7)
+	_ = aggregator.Collect(ctx, 4)
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{7, 4, 2}, results)
+}
+
+func TestTopK_SlowestQueries(t *testing.T) {
+	ctx := aggregator.RegisterTopKAggregator(context.Background(), 2, byDuration)
+	_ = aggregator.Collect(ctx, query{"SELECT 1", 5 * time.Millisecond})
+	_ = aggregator.Collect(ctx, query{"SELECT 2", 50 * time.Millisecond})
+	_ = aggregator.Collect(ctx, query{"SELECT 3", time.Millisecond})
+	_ = aggregator.Collect(ctx, query{"SELECT 4", 20 * time.Millisecond})
+
+	slowest, err := aggregator.AggregateWithTransform(ctx, func(q query) string { return q.SQL })
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SELECT 2", "SELECT 4"}, slowest)
+}
+
+func TestTopK_Smallest(t *testing.T) {
+	ctx := aggregator.RegisterTopKAggregator(context.Background(), 3, func(a, b int) int {
+		return cmp.Compare(b, a)
+	})
+	for _, v := range []int{5, 3, 9, 1, 7} {
+		_ = aggregator.Collect(ctx, v)
+	}
+
+	results, _ := aggregator.Aggregate[int](ctx)
+	assert.Equal(t, []int{1, 3, 5}, results)
+}
+
+func TestTopK_Concurrent(t *testing.T) {
+	ctx := aggregator.RegisterTopKAggregator(context.Background(), 10, cmp.Compare[int], aggregator.WithConcurrency())
+
+	for i := 0; i < 1000; i++ {
+		ctx, done := aggregator.WaitFuncOf[int](ctx)
+		go func(i int) {
+			defer done()
+			_ = aggregator.Collect(ctx, i)
+		}(i)
+	}
+
+	results, err := aggregator.Aggregate[int](ctx)
+	assert.NoError(t, err)
+
+	want := make([]int, 0, 10)
+	for i := 999; i >= 990; i-- {
+		want = append(want, i)
+	}
+	assert.Equal(t, want, results)
+}
+
+func TestTopK_ComparatorPanicReleasesLock(t *testing.T) {
+	agg := aggregator.NewTopK(1, func(a, b int) int {
+		if a < 0 || b < 0 {
+			panic("negative value")
+		}
+		return cmp.Compare(a, b)
+	}, aggregator.WithConcurrency())
+
+	agg.Collect(1)
+	assert.Panics(t, func() { agg.Collect(-1) })
+	agg.Collect(2)
+	assert.Equal(t, []int{2}, agg.Aggregate())
+}
+
+func TestTopK_MatchesSort(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	agg := aggregator.NewTopK(50, cmp.Compare[float64])
+
+	values := make([]float64, 0, 10_000)
+	for i := 0; i < 10_000; i++ {
+		v := r.NormFloat64()
+		values = append(values, v)
+		agg.Collect(v)
+	}
+
+	slices.SortFunc(values, func(a, b float64) int { return cmp.Compare(b, a) })
+	assert.Equal(t, values[:50], agg.Aggregate())
+}
+
+func TestTopK_DrainAndSeal(t *testing.T) {
+	ctx := aggregator.RegisterTopKAggregator(context.Background(), 2, cmp.Compare[int])
+	for i := 0; i < 5; i++ {
+		_ = aggregator.Collect(ctx, i)
+	}
+
+	drained, err := aggregator.Drain[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{4, 3}, drained)
+
+	_ = aggregator.Collect(ctx, 1)
+	results, err := aggregator.AggregateAndSeal[int](ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, results)
+	assert.Equal(t, aggregator.ErrAggregatorClosed, aggregator.Collect(ctx, 10))
+}
+
+func TestTopK_Inspect(t *testing.T) {
+	ctx := aggregator.RegisterTopKAggregator(context.Background(), 2, cmp.Compare[int])
+	for i := 0; i < 5; i++ {
+		_ = aggregator.Collect(ctx, i)
+	}
+
+	infos := aggregator.Inspect(ctx)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, aggregator.KindTopK, infos[0].Kind)
+	assert.Equal(t, 2, infos[0].Len)
+	assert.Equal(t, 3, infos[0].Dropped)
+}
+
+func TestTopK_InvalidSize(t *testing.T) {
+	assert.Panics(t, func() {
+		aggregator.NewTopK(0, cmp.Compare[int])
+	})
+}
@@ -0,0 +1,263 @@
+package aggregator_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "github.com/t-quanghuy/ctx-aggregator"
+)
+
+func atoi(_ context.Context, s string) (int, error) {
+	return strconv.Atoi(s)
+}
+
+func numbersContext() context.Context {
+	ctx := aggregator.Register[string](context.Background())
+	_ = aggregator.CollectMany(ctx, []string{"1", "x", "3", "y"})
+
+	return ctx
+}
+
+func TestAggregateWithTransformE_NoErrors(t *testing.T) {
+	ctx := aggregator.Register[string](context.Background())
+	_ = aggregator.CollectMany(ctx, []string{"1", "2"})
+
+	for _, policy := range []aggregator.ErrorPolicy{aggregator.FailFast, aggregator.SkipErrors, aggregator.PartialResults} {
+		results, err := aggregator.AggregateWithTransformE(ctx, atoi, policy)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, results)
+	}
+}
+
+func TestAggregateWithTransformE_FailFast(t *testing.T) {
+	calls := 0
+	results, err := aggregator.AggregateWithTransformE(numbersContext(), func(ctx context.Context, s string) (int, error) {
+		calls++
+		return atoi(ctx, s)
+	}, aggregator.FailFast)
+
+	assert.Nil(t, results)
+	assert.Equal(t, 2, calls)
+
+	var itemErr *aggregator.ItemError
+	assert.ErrorAs(t, err, &itemErr)
+	assert.Equal(t, 1, itemErr.Index)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+}
+
+func TestAggregateWithTransformE_SkipErrors(t *testing.T) {
+	results, err := aggregator.AggregateWithTransformE(numbersContext(), atoi, aggregator.SkipErrors)
+
+	assert.Equal(t, []int{1, 3}, results)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	assert.True(t, ok)
+	var indexes []int
+	for _, err := range joined.Unwrap() {
+		var itemErr *aggregator.ItemError
+		assert.ErrorAs(t, err, &itemErr)
+		indexes = append(indexes, itemErr.Index)
+	}
+	assert.Equal(t, []int{1, 3}, indexes)
+}
+
+func TestAggregateWithTransformE_PartialResults(t *testing.T) {
+	results, err := aggregator.AggregateWithTransformE(numbersContext(), atoi, aggregator.PartialResults)
+
+	assert.Equal(t, []int{1, 0, 3, 0}, results)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 2)
+}
+
+func TestAggregateWithTransformE_StopsOnCancellation(t *testing.T) {
+	for _, policy := range []aggregator.ErrorPolicy{aggregator.FailFast, aggregator.SkipErrors, aggregator.PartialResults} {
+		ctx, cancel := context.WithCancel(numbersContext())
+
+		calls := 0
+		results, err := aggregator.AggregateWithTransformE(ctx, func(ctx context.Context, s string) (int, error) {
+			calls++
+			if calls == 3 {
+				cancel()
+			}
+			return atoi(ctx, s)
+		}, policy)
+
+		switch policy {
+		case aggregator.FailFast:
+			// Stopped at the first failure before the cancellation
+			assert.Nil(t, results)
+			assert.ErrorIs(t, err, strconv.ErrSyntax)
+			assert.Equal(t, 2, calls)
+		case aggregator.SkipErrors:
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Equal(t, []int{1, 3}, results)
+			assert.ErrorIs(t, err, strconv.ErrSyntax)
+			assert.Equal(t, 3, calls)
+		case aggregator.PartialResults:
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Equal(t, []int{1, 0, 3}, results)
+			assert.Equal(t, 3, calls)
+		}
+		cancel()
+	}
+}
+
+func TestAggregateWithTransformE_FailFastOnCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(numbersContext())
+	cancel()
+
+	calls := 0
+	results, err := aggregator.AggregateWithTransformE(ctx, func(ctx context.Context, s string) (int, error) {
+		calls++
+		return atoi(ctx, s)
+	}, aggregator.FailFast)
+
+	assert.Nil(t, results)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, calls)
+}
+
+func TestAggregateWithTransformE_TransformSeesContext(t *testing.T) {
+	type requestID struct{}
+	ctx := context.WithValue(numbersContext(), requestID{}, "req-1")
+
+	results, err := aggregator.AggregateWithTransformE(ctx, func(ctx context.Context, s string) (string, error) {
+		return ctx.Value(requestID{}).(string) + ":" + s, nil
+	}, aggregator.FailFast)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"req-1:1", "req-1:x", "req-1:3", "req-1:y"}, results)
+}
+
+func TestAggregateWithTransformE_Key(t *testing.T) {
+	key := aggregator.NewKey[string]("numbers")
+	ctx := aggregator.RegisterKey(context.Background(), key)
+	assert.NoError(t, aggregator.CollectKey(ctx, key, "7"))
+
+	results, err := aggregator.AggregateWithTransformEKey(ctx, key, atoi, aggregator.FailFast)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{7}, results)
+}
+
+func TestAggregateWithTransformE_NotFound(t *testing.T) {
+	_, err := aggregator.AggregateWithTransformE(context.Background(), atoi, aggregator.SkipErrors)
+	assert.True(t, errors.Is(err, aggregator.ErrNotFoundAggregator))
+}
+
+func TestAggregateWithParallelTransform_KeepsOrder(t *testing.T) {
+	for _, workers := range []int{0, 1, 3, 64} {
+		ctx := aggregator.Register[int](context.Background(), aggregator.WithConcurrency())
+		assert.NoError(t, aggregator.CollectMany(ctx, intRange(1000)))
+
+		results, err := aggregator.AggregateWithParallelTransform(ctx, func(n int) string {
+			return strconv.Itoa(n)
+		}, workers)
+		assert.NoError(t, err)
+
+		want := make([]string, 1000)
+		for i := range want {
+			want[i] = strconv.Itoa(i)
+		}
+		assert.Equal(t, want, results)
+	}
+}
+
+func TestAggregateWithParallelTransform_Empty(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background())
+
+	results, err := aggregator.AggregateWithParallelTransform(ctx, strconv.Itoa, 4)
+	assert.NoError(t, err)
+	assert.NotNil(t, results)
+	assert.Empty(t, results)
+}
+
+func TestAggregateWithParallelTransform_RecoversPanics(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background())
+	assert.NoError(t, aggregator.CollectMany(ctx, intRange(100)))
+
+	results, err := aggregator.AggregateWithParallelTransform(ctx, func(n int) int {
+		if n == 42 {
+			panic("boom")
+		}
+		return n
+	}, 4)
+	assert.Nil(t, results)
+
+	var itemErr *aggregator.ItemError
+	assert.ErrorAs(t, err, &itemErr)
+	assert.Equal(t, 42, itemErr.Index)
+
+	var panicErr *aggregator.PanicError
+	assert.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+}
+
+func TestAggregateWithParallelTransform_StopsOnCancellation(t *testing.T) {
+	ctx := aggregator.Register[int](context.Background())
+	assert.NoError(t, aggregator.CollectMany(ctx, intRange(10000)))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	const workers = 4
+	var calls atomic.Int64
+	results, err := aggregator.AggregateWithParallelTransform(ctx, func(n int) int {
+		switch call := calls.Add(1); {
+		case call == 10:
+			cancel()
+		case call > 10:
+			// Items started after the 10th only finish once it is cancelled
+			<-ctx.Done()
+		}
+		return n
+	}, workers)
+
+	assert.Nil(t, results)
+	assert.Equal(t, context.Canceled, err)
+	// Every worker stops after its current item
+	assert.LessOrEqual(t, calls.Load(), int64(10+workers-1))
+}
+
+func TestAggregateWithParallelTransform_Key(t *testing.T) {
+	key := aggregator.NewKey[int]("numbers")
+	ctx := aggregator.RegisterKey(context.Background(), key)
+	assert.NoError(t, aggregator.CollectKey(ctx, key, 7))
+
+	results, err := aggregator.AggregateWithParallelTransformKey(ctx, key, strconv.Itoa, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"7"}, results)
+}
+
+func TestAggregateWithParallelTransform_NotFound(t *testing.T) {
+	_, err := aggregator.AggregateWithParallelTransform(context.Background(), strconv.Itoa, 2)
+	assert.Equal(t, aggregator.ErrNotFoundAggregator, err)
+}
+
+func BenchmarkParallelTransform(b *testing.B) {
+	ctx := aggregator.Register[int](context.Background())
+	_ = aggregator.CollectMany(ctx, intRange(10000))
+
+	heavy := func(n int) int {
+		for i := 0; i < 1000; i++ {
+			n = n*31 + i
+		}
+		return n
+	}
+
+	b.Run("Sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = aggregator.AggregateWithTransform(ctx, heavy)
+		}
+	})
+
+	b.Run("Parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = aggregator.AggregateWithParallelTransform(ctx, heavy, 0)
+		}
+	})
+}
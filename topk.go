@@ -0,0 +1,145 @@
+package aggregator
+
+import (
+	"context"
+	"slices"
+)
+
+var _ SealableAggregator[any] = new(topKAggregator[any])
+var _ BoundedAggregator[any] = new(topKAggregator[any])
+var _ inspector = new(topKAggregator[any])
+var _ ConcurrentContextAggregator[any] = new(concurrentTopKAggregator[any])
+var _ SealableAggregator[any] = new(concurrentTopKAggregator[any])
+var _ BoundedAggregator[any] = new(concurrentTopKAggregator[any])
+var _ inspector = new(concurrentTopKAggregator[any])
+
+// RegisterTopKAggregator registers an aggregator keeping the k greatest items
+// according to cmp, e.g. the slowest queries of a request. cmp returns a
+// positive number when a is greater than b, like cmp.Compare; invert it to keep
+// the k smallest items. Memory is O(k) and Aggregate returns the items greatest
+// first. Among equal items, the first collected are kept.
+//
+// WithConcurrency, WithKey and WithLateCollectHook are honored, other options
+// are ignored. RegisterTopKAggregator panics if k is not positive.
+func RegisterTopKAggregator[T any](ctx context.Context, k int, cmp func(a, b T) int, opts ...Option) context.Context {
+	cfg := newConfig(opts...)
+	return registerAggregator(ctx, typedContextKey[T](cfg.keys...), newTopKAggregator(k, cmp, cfg))
+}
+
+// NewTopK creates a top-k aggregator without registering it into a context
+func NewTopK[T any](k int, cmp func(a, b T) int, opts ...Option) ContextAggregator[T] {
+	return newTopKAggregator(k, cmp, newConfig(opts...))
+}
+
+func newTopKAggregator[T any](k int, cmp func(a, b T) int, cfg *config) ContextAggregator[T] {
+	if k <= 0 {
+		panic("aggregator: top-k size must be positive")
+	}
+
+	h := &topK[T]{
+		items: make([]T, 0, k),
+		k:     k,
+		cmp:   cmp,
+	}
+	if cfg.concurrent {
+		agg := &concurrentTopKAggregator[T]{}
+		agg.init(h, KindConcurrentTopK, cfg)
+		return agg
+	}
+
+	agg := &topKAggregator[T]{}
+	agg.init(h, KindTopK, cfg)
+	return agg
+}
+
+// topK is a min-heap of the k greatest items seen, its root being the smallest
+// of them
+type topK[T any] struct {
+	items []T
+	k     int
+	cmp   func(a, b T) int
+	// rejected counts the items not kept or evicted by greater ones
+	rejected int
+}
+
+func (h *topK[T]) add(data T) {
+	if len(h.items) < h.k {
+		h.items = append(h.items, data)
+		h.up(len(h.items) - 1)
+		return
+	}
+
+	h.rejected++
+	if h.cmp(data, h.items[0]) > 0 {
+		h.items[0] = data
+		h.down(0)
+	}
+}
+
+func (h *topK[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if h.cmp(h.items[i], h.items[parent]) >= 0 {
+			return
+		}
+		h.items[i], h.items[parent] = h.items[parent], h.items[i]
+		i = parent
+	}
+}
+
+func (h *topK[T]) down(i int) {
+	for {
+		smallest := i
+		for _, child := range [2]int{2*i + 1, 2*i + 2} {
+			if child < len(h.items) && h.cmp(h.items[child], h.items[smallest]) < 0 {
+				smallest = child
+			}
+		}
+		if smallest == i {
+			return
+		}
+		h.items[i], h.items[smallest] = h.items[smallest], h.items[i]
+		i = smallest
+	}
+}
+
+// snapshot returns the kept items greatest first
+func (h *topK[T]) snapshot() []T {
+	items := slices.Clone(h.items)
+	slices.SortStableFunc(items, func(a, b T) int {
+		return h.cmp(b, a)
+	})
+
+	return items
+}
+
+func (h *topK[T]) drain() []T {
+	items := h.snapshot()
+	clear(h.items)
+	h.items = h.items[:0]
+
+	return items
+}
+
+func (h *topK[T]) dropped() int {
+	return h.rejected
+}
+
+func (h *topK[T]) info() AggregatorInfo {
+	return AggregatorInfo{
+		Len:      len(h.items),
+		Cap:      h.k,
+		MaxItems: h.k,
+		Dropped:  h.rejected,
+	}
+}
+
+// topKAggregator is a sequential top-k aggregator
+type topKAggregator[T any] struct {
+	sequentialBounded[T, *topK[T]]
+}
+
+// concurrentTopKAggregator is a thread-safe top-k aggregator
+type concurrentTopKAggregator[T any] struct {
+	synchronizedBounded[T, *topK[T]]
+}
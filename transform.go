@@ -0,0 +1,193 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// TransformFuncE transforms an item of type T to type R. It can fail, and it is
+// given the context of the aggregation so it can stop once the context is done.
+type TransformFuncE[T any, R any] func(context.Context, T) (R, error)
+
+// ErrorPolicy decides what a fallible transform does with the items it fails on
+type ErrorPolicy int
+
+const (
+	// FailFast stops at the first failure and returns no results
+	FailFast ErrorPolicy = iota
+	// SkipErrors leaves out the failed items, returning the results of the others
+	// along with the joined errors
+	SkipErrors
+	// PartialResults keeps the zero value of R in place of every failed item, so
+	// results stay aligned with the aggregated items, and returns the joined errors
+	PartialResults
+)
+
+// ItemError reports that a fallible transform failed on the item at Index in
+// the aggregated items. It wraps the error returned by the transform.
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError reports a panic recovered from a transform, wrapped in an
+// *ItemError carrying the index of the item
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("transform panicked: %v", e.Value)
+}
+
+// AggregateWithTransformE aggregates and transforms items with a transform that
+// can fail. Failures are returned as *ItemError, joined with errors.Join unless
+// policy is FailFast, and handled as policy says.
+//
+// The context is checked before every item. Once it is done the transform stops
+// whatever the policy, and the context error is returned along with, unless
+// policy is FailFast, the results and errors so far.
+func AggregateWithTransformE[T any, R any](ctx context.Context, transform TransformFuncE[T, R], policy ErrorPolicy, keys ...string) ([]R, error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return transformItemsE(ctx, agg.Aggregate(), transform, policy)
+}
+
+// AggregateWithTransformEKey is AggregateWithTransformE for an aggregator
+// registered under key
+func AggregateWithTransformEKey[T any, R any](ctx context.Context, key *Key[T], transform TransformFuncE[T, R], policy ErrorPolicy) ([]R, error) {
+	agg, err := lookupAggregator[T](ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return transformItemsE(ctx, agg.Aggregate(), transform, policy)
+}
+
+func transformItemsE[T any, R any](ctx context.Context, items []T, transform TransformFuncE[T, R], policy ErrorPolicy) ([]R, error) {
+	results := make([]R, 0, len(items))
+	var errs []error
+	var zero R
+
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			if policy == FailFast {
+				return nil, err
+			}
+			return results, errors.Join(append(errs, err)...)
+		}
+
+		result, err := transform(ctx, item)
+		if err == nil {
+			results = append(results, result)
+			continue
+		}
+
+		err = &ItemError{Index: i, Err: err}
+		switch policy {
+		case SkipErrors:
+			errs = append(errs, err)
+		case PartialResults:
+			errs = append(errs, err)
+			results = append(results, zero)
+		default:
+			return nil, err
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// AggregateWithParallelTransform is AggregateWithTransform spreading the
+// transform over workers goroutines, for CPU-heavy transforms of many items.
+// workers of zero or less uses GOMAXPROCS goroutines. Results keep the order
+// of the aggregated items.
+//
+// Once ctx is done the workers stop and the context error is returned. A panic
+// in transform stops the workers too and is returned as an *ItemError wrapping
+// a *PanicError. No results are returned on error.
+func AggregateWithParallelTransform[T any, R any](ctx context.Context, transform TransformFunc[T, R], workers int, keys ...string) ([]R, error) {
+	agg, err := extractAggregator[T](ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parallelTransform(ctx, agg.Aggregate(), transform, workers)
+}
+
+// AggregateWithParallelTransformKey is AggregateWithParallelTransform for an
+// aggregator registered under key
+func AggregateWithParallelTransformKey[T any, R any](ctx context.Context, key *Key[T], transform TransformFunc[T, R], workers int) ([]R, error) {
+	agg, err := lookupAggregator[T](ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return parallelTransform(ctx, agg.Aggregate(), transform, workers)
+}
+
+func parallelTransform[T any, R any](ctx context.Context, items []T, transform TransformFunc[T, R], workers int) ([]R, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(items))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// Workers take the next item from a shared index, so a slow item does not
+	// hold back a whole range, and write its result in place to keep order
+	results := make([]R, len(items))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= len(items) {
+					return
+				}
+				if err := transformItem(transform, items[i], i, &results[i]); err != nil {
+					cancel(err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// transformItem transforms item into result, recovering a panic into an error
+func transformItem[T any, R any](transform TransformFunc[T, R], item T, index int, result *R) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &ItemError{Index: index, Err: &PanicError{Value: r, Stack: debug.Stack()}}
+		}
+	}()
+
+	*result = transform(item)
+	return nil
+}